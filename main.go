@@ -5,7 +5,9 @@ import (
 	"flag"
 	"log"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6/tf6server"
+
+	"github.com/mattclegg/terraform-provider-sops/sops/sops"
 )
 
 func main() {
@@ -14,14 +16,19 @@ func main() {
 	flag.BoolVar(&debugMode, "debug", false, "set to true to run the provider with support for debuggers like delve")
 	flag.Parse()
 
-	opts := &plugin.ServeOpts{ProviderFunc: Provider}
+	ctx := context.Background()
+	serverFactory, err := sops.ProviderServerFactory(ctx)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	var serveOpts []tf6server.ServeOpt
 	if debugMode {
-		err := plugin.Debug(context.Background(), "registry.terraform.io/mattclegg/sops", opts)
-		if err != nil {
-			log.Fatal(err.Error())
-		}
-		return
+		serveOpts = append(serveOpts, tf6server.WithManagedDebug())
 	}
 
-	plugin.Serve(opts)
+	err = tf6server.Serve("registry.terraform.io/mattclegg/sops", serverFactory, serveOpts...)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
 }
@@ -0,0 +1,111 @@
+package sops
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// expandHome replaces a leading ~ in filename with the home directory of the
+// user running Terraform
+func expandHome(filename string) (string, error) {
+	if filename != "~" && !strings.HasPrefix(filename, "~/") {
+		return filename, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("can't expand %s: %s", filename, err)
+	}
+	return filepath.Join(home, filename[1:]), nil
+}
+
+// readAgeRecipientsFile reads a recipients file in the format of age -R: one
+// recipient per line, ignoring empty lines and # comments
+func readAgeRecipientsFile(filename string) ([]string, error) {
+	filename, err := expandHome(filename)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recipients []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := validateAgeRecipient(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", filename, n, err)
+		}
+		recipients = append(recipients, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", filename, err)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%s holds no recipient", filename)
+	}
+	return recipients, nil
+}
+
+// readAgeIdentityFile returns the public keys of the identities of an age
+// key file, such as the keys.txt read by sops to decrypt
+func readAgeIdentityFile(filename string) ([]string, error) {
+	filename, err := expandHome(filename)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not an age identity file: %s", filename, err)
+	}
+	var recipients []string
+	for _, identity := range identities {
+		x25519, ok := identity.(*age.X25519Identity)
+		if !ok {
+			return nil, fmt.Errorf("%s holds an identity of unsupported type %T", filename, identity)
+		}
+		recipients = append(recipients, x25519.Recipient().String())
+	}
+	return recipients, nil
+}
+
+// ageFileRecipients returns the recipients read from the recipients_file and
+// identity_file attributes of the age configuration at p
+func ageFileRecipients(p path.Path, recipientsFile, identityFile types.String) ([]string, diag.Diagnostics) {
+	var recipients []string
+	var diags diag.Diagnostics
+	if name := recipientsFile.ValueString(); name != "" {
+		fileRecipients, err := readAgeRecipientsFile(name)
+		if err != nil {
+			diags.AddAttributeError(p.AtName("recipients_file"), "Invalid age recipients file", err.Error())
+		}
+		recipients = append(recipients, fileRecipients...)
+	}
+	if name := identityFile.ValueString(); name != "" {
+		identityRecipients, err := readAgeIdentityFile(name)
+		if err != nil {
+			diags.AddAttributeError(p.AtName("identity_file"), "Invalid age identity file", err.Error())
+		}
+		recipients = append(recipients, identityRecipients...)
+	}
+	return recipients, diags
+}
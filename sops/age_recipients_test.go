@@ -0,0 +1,126 @@
+package sops
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"golang.org/x/crypto/ssh"
+)
+
+const testSshEd25519Recipient = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIL0vZ6OeJHGYwZt0AmGYhw3T6kYk1X+AUMvMBNHaVHiP user@example.com"
+
+func TestReadAgeRecipientsFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	content := fmt.Sprintf("# team keys\n\n%s\n", testAgeRecipient)
+	if err := ioutil.WriteFile(filepath.Join(home, "recipients.txt"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	recipients, err := readAgeRecipientsFile("~/recipients.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(recipients) != 1 || recipients[0] != testAgeRecipient {
+		t.Errorf("expected the recipient of the file, got %v", recipients)
+	}
+}
+
+func TestReadAgeRecipientsFile_invalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "recipients.txt")
+	content := fmt.Sprintf("%s\nage1invalid\n", testAgeRecipient)
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := readAgeRecipientsFile(filename)
+	if err == nil || !strings.HasPrefix(err.Error(), filename+":2: ") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
+
+func TestReadAgeIdentityFile(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "keys.txt")
+	content := fmt.Sprintf("# public key: %s\n%s\n", identity.Recipient(), identity)
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	recipients, err := readAgeIdentityFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(recipients) != 1 || recipients[0] != identity.Recipient().String() {
+		t.Errorf("expected the public key of the identity, got %v", recipients)
+	}
+}
+
+func TestValidateAgeRecipient_ssh(t *testing.T) {
+	if err := validateAgeRecipient(testSshEd25519Recipient); err != nil {
+		t.Errorf("expected SSH recipients to be accepted, got %v", err)
+	}
+	if err := validateAgeRecipient("ssh-ed25519 invalid"); err == nil || !strings.Contains(err.Error(), "not an SSH public key") {
+		t.Errorf("expected an invalid SSH key to be reported, got %v", err)
+	}
+}
+
+func TestAgeMasterKeys_ssh(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOPS_AGE_SSH_PRIVATE_KEY_FILE", keyFile)
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublic)))
+
+	ctx := context.Background()
+	ageKeys, err := ageMasterKeys([]string{recipient})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	store := storeForFormat(formats.Yaml)
+	encrypted, err := Encrypt(ctx, EncryptOpts{
+		Cipher:      aes.NewCipher(),
+		InputStore:  store,
+		OutputStore: store,
+		InputPath:   "secret.yaml",
+		KeyServices: LocalKeySvc(ctx),
+		KeyGroups:   []mozillasops.KeyGroup{ageKeys},
+	}, []byte("hello: world\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cleartext, err := decryptData(ctx, encrypted, "yaml")
+	if err != nil {
+		t.Fatalf("expected the file to be decrypted with the SSH key, got %s", err)
+	}
+	if string(cleartext) != "hello: world\n" {
+		t.Errorf("unexpected cleartext %q", cleartext)
+	}
+}
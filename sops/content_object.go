@@ -0,0 +1,103 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// contentObjectBranches converts the value of content_object into the tree
+// sops encrypts, without encoding it to a string first. Keys are sorted, so
+// the same object always produces the same file.
+func contentObjectBranches(ctx context.Context, v attr.Value) (mozillasops.TreeBranches, error) {
+	tree, err := contentObjectTree(v)
+	if err != nil {
+		return nil, err
+	}
+	branch, ok := tree.(mozillasops.TreeBranch)
+	if !ok {
+		return nil, fmt.Errorf("content_object must be an object or a map, got %s", typeName(ctx, v))
+	}
+	return mozillasops.TreeBranches{branch}, nil
+}
+
+func contentObjectTree(v attr.Value) (interface{}, error) {
+	if v.IsUnknown() {
+		return nil, fmt.Errorf("content_object is not known yet")
+	}
+	if v.IsNull() {
+		return nil, nil
+	}
+	switch typed := v.(type) {
+	case types.Dynamic:
+		return contentObjectTree(typed.UnderlyingValue())
+	case types.Object:
+		return contentObjectMap(typed.Attributes())
+	case types.Map:
+		return contentObjectMap(typed.Elements())
+	case types.Tuple:
+		return contentObjectList(typed.Elements())
+	case types.List:
+		return contentObjectList(typed.Elements())
+	case types.Set:
+		return contentObjectList(typed.Elements())
+	case types.String:
+		return typed.ValueString(), nil
+	case types.Bool:
+		return typed.ValueBool(), nil
+	case types.Number:
+		number := typed.ValueBigFloat()
+		if number.IsInt() {
+			if i, accuracy := number.Int64(); accuracy == 0 {
+				return int(i), nil
+			}
+		}
+		f, _ := number.Float64()
+		return f, nil
+	case types.Int64:
+		return int(typed.ValueInt64()), nil
+	case types.Float64:
+		return typed.ValueFloat64(), nil
+	}
+	return nil, fmt.Errorf("unsupported value %s in content_object", v)
+}
+
+func contentObjectMap(attrs map[string]attr.Value) (interface{}, error) {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	branch := make(mozillasops.TreeBranch, 0, len(keys))
+	for _, k := range keys {
+		value, err := contentObjectTree(attrs[k])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", k, err)
+		}
+		branch = append(branch, mozillasops.TreeItem{Key: k, Value: value})
+	}
+	return branch, nil
+}
+
+func contentObjectList(elems []attr.Value) (interface{}, error) {
+	list := make([]interface{}, len(elems))
+	for i, elem := range elems {
+		value, err := contentObjectTree(elem)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %s", i, err)
+		}
+		list[i] = value
+	}
+	return list, nil
+}
+
+func typeName(ctx context.Context, v attr.Value) string {
+	if dynamic, ok := v.(types.Dynamic); ok && dynamic.UnderlyingValue() != nil {
+		v = dynamic.UnderlyingValue()
+	}
+	return v.Type(ctx).String()
+}
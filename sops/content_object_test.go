@@ -0,0 +1,67 @@
+package sops
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func testContentObject(t *testing.T) types.Dynamic {
+	t.Helper()
+	servers, diags := types.TupleValue([]attr.Type{types.StringType, types.NumberType}, []attr.Value{
+		types.StringValue("web"),
+		types.NumberValue(big.NewFloat(8080)),
+	})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	obj, diags := types.ObjectValue(map[string]attr.Type{
+		"zone":    types.StringType,
+		"enabled": types.BoolType,
+		"ratio":   types.NumberType,
+		"servers": servers.Type(context.Background()),
+		"empty":   types.StringType,
+	}, map[string]attr.Value{
+		"zone":    types.StringValue("eu"),
+		"enabled": types.BoolValue(true),
+		"ratio":   types.NumberValue(big.NewFloat(0.5)),
+		"servers": servers,
+		"empty":   types.StringNull(),
+	})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	return types.DynamicValue(obj)
+}
+
+func TestContentObjectBranches(t *testing.T) {
+	branches, err := contentObjectBranches(context.Background(), testContentObject(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for format, expected := range map[formats.Format]string{
+		formats.Yaml: "empty: null\nenabled: true\nratio: 0.5\nservers:\n    - web\n    - 8080\nzone: eu\n",
+		formats.Json: "{\n\t\"empty\": null,\n\t\"enabled\": true,\n\t\"ratio\": 0.5,\n\t\"servers\": [\n\t\t\"web\",\n\t\t8080\n\t],\n\t\"zone\": \"eu\"\n}\n",
+	} {
+		emitted, err := storeForFormat(format).EmitPlainFile(branches)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(emitted) != expected {
+			t.Errorf("unexpected output %q", emitted)
+		}
+	}
+}
+
+func TestContentObjectBranches_notObject(t *testing.T) {
+	if _, err := contentObjectBranches(context.Background(), types.DynamicValue(types.StringValue("hello"))); err == nil {
+		t.Error("expected a string to be rejected")
+	}
+	if _, err := contentObjectBranches(context.Background(), types.DynamicUnknown()); err == nil {
+		t.Error("expected an unknown value to be rejected")
+	}
+}
@@ -1,52 +1,80 @@
 package sops
 
 import (
+	"context"
 	"io/ioutil"
 	"strings"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
 func dataSourceExternal() *schema.Resource {
 	return &schema.Resource{
-		Read: dataSourceExternalRead,
+		ReadContext: dataSourceExternalRead,
 
-		Schema: map[string]*schema.Schema{
+		Schema: withFlattenSchema(map[string]*schema.Schema{
 			"input_type": {
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
 				ForceNew: true,
+				Default:  "auto",
 			},
 			"source": {
 				Type:     schema.TypeString,
 				Required: true,
 				ForceNew: true,
 			},
+			"ini_options": iniOptionsSchema(),
 
 			"data": &schema.Schema{
 				Type:      schema.TypeMap,
 				Computed:  true,
 				Sensitive: true,
 			},
+			"nonsensitive_data": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The values of data that sops left unencrypted.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"documents": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The flattened data of each document of a multi-document YAML file.",
+				Elem: &schema.Schema{
+					Type: schema.TypeMap,
+					Elem: &schema.Schema{
+						Type: schema.TypeString,
+					},
+				},
+			},
 			"raw": &schema.Schema{
 				Type:      schema.TypeString,
 				Computed:  true,
 				Sensitive: true,
 			},
-		},
+		}),
 	}
 }
 
-func dataSourceExternalRead(d *schema.ResourceData, meta interface{}) error {
+func dataSourceExternalRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	source := d.Get("source").(string)
 	content, err := ioutil.ReadAll(strings.NewReader(source))
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
 	format := d.Get("input_type").(string)
-	if err := validateInputType(format); err != nil {
-		return err
+	format, err = resolveInputType(format, content)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := readData(ctx, content, format, d); err != nil {
+		return diag.FromErr(err)
 	}
-	return readData(content, format, d)
+	return nil
 }
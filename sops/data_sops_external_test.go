@@ -192,3 +192,33 @@ func TestDataSourceSopsExternal_json(t *testing.T) {
 		},
 	})
 }
+
+const configTestDataSourceSopsExternal_auto = `
+data "local_file" "test_auto" {
+  filename = "%s/test-fixtures/basic.json"
+}
+
+data "sops_external" "test_auto" {
+  source     = "${data.local_file.test_auto.content}"
+  input_type = "auto"
+}`
+
+func TestDataSourceSopsExternal_auto(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsExternal_auto, wd)
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_external.test_auto", "data.hello", "world"),
+					resource.TestCheckResourceAttr("data.sops_external.test_auto", "data.integer", "0"),
+				),
+			},
+		},
+	})
+}
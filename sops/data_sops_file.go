@@ -1,18 +1,18 @@
 package sops
 
 import (
-	"fmt"
+	"context"
 	"io/ioutil"
-	"path"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
 func dataSourceFile() *schema.Resource {
 	return &schema.Resource{
-		Read: dataSourceFileRead,
+		ReadContext: dataSourceFileRead,
 
-		Schema: map[string]*schema.Schema{
+		Schema: withFlattenSchema(map[string]*schema.Schema{
 			"input_type": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -23,49 +23,60 @@ func dataSourceFile() *schema.Resource {
 				Required: true,
 				ForceNew: true,
 			},
+			"ini_options": iniOptionsSchema(),
 
 			"data": &schema.Schema{
 				Type:      schema.TypeMap,
 				Computed:  true,
 				Sensitive: true,
 			},
+			"nonsensitive_data": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The values of data that sops left unencrypted.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"documents": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The flattened data of each document of a multi-document YAML file.",
+				Elem: &schema.Schema{
+					Type: schema.TypeMap,
+					Elem: &schema.Schema{
+						Type: schema.TypeString,
+					},
+				},
+			},
 			"raw": &schema.Schema{
 				Type:      schema.TypeString,
 				Computed:  true,
 				Sensitive: true,
 			},
-		},
+		}),
 	}
 }
 
-func dataSourceFileRead(d *schema.ResourceData, meta interface{}) error {
+func dataSourceFileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	sourceFile := d.Get("source_file").(string)
 	content, err := ioutil.ReadFile(sourceFile)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
-	var format string
-	if input_type := d.Get("input_type").(string); input_type != "" {
-		format = input_type
-	} else {
-		switch ext := path.Ext(sourceFile); ext {
-		case ".json":
-			format = "json"
-		case ".yaml", ".yml":
-			format = "yaml"
-		case ".env":
-			format = "dotenv"
-		case ".ini":
-			format = "ini"
-		default:
-			return fmt.Errorf("Don't know how to decode file with extension %s, set input_type to json, yaml or raw as appropriate", ext)
-		}
+	format, err := sourceFileFormat(sourceFile, d.Get("input_type").(string))
+	if err != nil {
+		return diag.FromErr(err)
 	}
-
-	if err := validateInputType(format); err != nil {
-		return err
+	format, err = resolveInputType(format, content)
+	if err != nil {
+		return diag.FromErr(err)
 	}
 
-	return readData(content, format, d)
+	if err := readData(ctx, content, format, d); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
 }
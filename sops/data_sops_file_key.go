@@ -1,19 +1,20 @@
 package sops
 
 import (
-	"fmt"
+	"context"
 	"io/ioutil"
 	"os"
-	"path"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
 func dataSourceFileKey() *schema.Resource {
 	return &schema.Resource{
-		Read: dataSourceFileKeyRead,
+		ReadContext: dataSourceFileKeyRead,
 
-		Schema: map[string]*schema.Schema{
+		Schema: withFlattenSchema(map[string]*schema.Schema{
 			"input_type": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -34,11 +35,20 @@ func dataSourceFileKey() *schema.Resource {
 				Optional: true,
 				ForceNew: true,
 			},
+			"ini_options": iniOptionsSchema(),
 			"data": {
 				Type:      schema.TypeString,
 				Computed:  true,
 				Sensitive: true,
 			},
+			"nonsensitive_data": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The values of map that sops left unencrypted.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
 			"yaml": {
 				Type:      schema.TypeString,
 				Computed:  true,
@@ -54,44 +64,34 @@ func dataSourceFileKey() *schema.Resource {
 				Computed:  true,
 				Sensitive: true,
 			},
-		},
+		}),
 	}
 }
 
-func dataSourceFileKeyRead(d *schema.ResourceData, meta interface{}) error {
+func dataSourceFileKeyRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	sourceFile := d.Get("source_file").(string)
 	content, err := ioutil.ReadFile(sourceFile)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 	if ageKeyFile := d.Get("age_key_file").(string); ageKeyFile != "" {
 		envVarName := "SOPS_AGE_KEY_FILE"
 		err := os.Setenv(envVarName, ageKeyFile)
 		if err != nil {
-			log.Errorf("fail to set environment variable %s.Error is %s", envVarName, err)
+			tflog.SubsystemWarn(withLogSubsystem(ctx, logKeyservice), logKeyservice, "Failed to set the age key file", map[string]interface{}{"variable": envVarName, "error": err.Error()})
 		}
 	}
-	var format string
-	if inputType := d.Get("input_type").(string); inputType != "" {
-		format = inputType
-	} else {
-		switch ext := path.Ext(sourceFile); ext {
-		case ".json":
-			format = "json"
-		case ".yaml", ".yml":
-			format = "yaml"
-		case ".env":
-			format = "dotenv"
-		case ".ini":
-			format = "ini"
-		default:
-			return fmt.Errorf("Don't know how to decode file with extension %s, set input_type to json, yaml or raw as appropriate", ext)
-		}
+	format, err := sourceFileFormat(sourceFile, d.Get("input_type").(string))
+	if err != nil {
+		return diag.FromErr(err)
 	}
-
-	if err := validateInputType(format); err != nil {
-		return err
+	format, err = resolveInputType(format, content)
+	if err != nil {
+		return diag.FromErr(err)
 	}
 	dataKey := d.Get("data_key").(string)
-	return readDataKey(content, format, dataKey, d)
+	if err := readDataKey(ctx, content, format, dataKey, d); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
 }
@@ -163,3 +163,118 @@ func TestDataSourceSopsFile_json(t *testing.T) {
 		},
 	})
 }
+
+const configTestDataSourceSopsFile_iniNested = `
+data "sops_file" "test_ini" {
+  source_file = "%s/test-fixtures/nested.ini"
+  ini_options {
+    nested_sections = true
+    typed_values    = true
+  }
+}`
+
+func TestDataSourceSopsFile_iniNested(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_iniNested, wd)
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_ini", "data.rootKey", "foo"),
+					resource.TestCheckResourceAttr("data.sops_file.test_ini", "data.server.port", "8080"),
+					resource.TestCheckResourceAttr("data.sops_file.test_ini", "data.server.enabled", "true"),
+					resource.TestCheckResourceAttr("data.sops_file.test_ini", "data.server.tls.cert", "a.pem"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_bracketIndexes = `
+data "sops_file" "test_list" {
+  source_file       = "%s/test-fixtures/complex-list.yaml"
+  flatten_separator = "/"
+  list_index_format = "bracket"
+}`
+
+func TestDataSourceSopsFile_bracketIndexes(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_bracketIndexes, wd)
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_list", "data.a_list[0]/name", "foo"),
+					resource.TestCheckResourceAttr("data.sops_file.test_list", "data.a_list[1]/index", "1"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_nonsensitive = `
+data "sops_file" "test_nonsensitive" {
+  source_file = "%s/test-fixtures/unencrypted-regex.yaml"
+}`
+
+func TestDataSourceSopsFile_nonsensitive(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_nonsensitive, wd)
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_nonsensitive", "data.db.password", "secret"),
+					resource.TestCheckResourceAttr("data.sops_file.test_nonsensitive", "nonsensitive_data.%", "4"),
+					resource.TestCheckResourceAttr("data.sops_file.test_nonsensitive", "nonsensitive_data.region", "eu-west-1"),
+					resource.TestCheckResourceAttr("data.sops_file.test_nonsensitive", "nonsensitive_data.hosts.1", "b.example"),
+					resource.TestCheckResourceAttr("data.sops_file.test_nonsensitive", "nonsensitive_data.db.region", "inner"),
+					resource.TestCheckNoResourceAttr("data.sops_file.test_nonsensitive", "nonsensitive_data.db.password"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFile_multiDocument = `
+data "sops_file" "test_multi_document" {
+  source_file = "%s/test-fixtures/multi-document.yaml"
+}`
+
+func TestDataSourceSopsFile_multiDocument(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFile_multiDocument, wd)
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_file.test_multi_document", "data.data.password", "hunter2"),
+					resource.TestCheckResourceAttr("data.sops_file.test_multi_document", "documents.#", "2"),
+					resource.TestCheckResourceAttr("data.sops_file.test_multi_document", "documents.0.metadata.name", "db"),
+					resource.TestCheckResourceAttr("data.sops_file.test_multi_document", "documents.1.metadata.name", "api"),
+					resource.TestCheckResourceAttr("data.sops_file.test_multi_document", "documents.1.data.token", "abc123"),
+				),
+			},
+		},
+	})
+}
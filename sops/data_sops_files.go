@@ -0,0 +1,350 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/mattclegg/terraform-provider-sops/sops/sops/internal/ini"
+)
+
+var _ datasource.DataSource = &dataSourceFiles{}
+
+// dataSourceFiles decrypts every file of a directory matching glob patterns.
+// It is a framework data source, since the SDK can't key objects by path.
+type dataSourceFiles struct{}
+
+type dataSourceFilesModel struct {
+	flattenOptionsModel
+	ID          types.String     `tfsdk:"id"`
+	Directory   types.String     `tfsdk:"directory"`
+	Include     types.List       `tfsdk:"include"`
+	Exclude     types.List       `tfsdk:"exclude"`
+	InputType   types.String     `tfsdk:"input_type"`
+	Concurrency types.Int64      `tfsdk:"concurrency"`
+	IniOptions  *iniOptionsModel `tfsdk:"ini_options"`
+	Files       types.Map        `tfsdk:"files"`
+	Raw         types.Map        `tfsdk:"raw"`
+}
+
+// decryptedFileModel is an element of the files attribute
+type decryptedFileModel struct {
+	Path             types.String `tfsdk:"path"`
+	Format           types.String `tfsdk:"format"`
+	Data             types.Map    `tfsdk:"data"`
+	NonsensitiveData types.Map    `tfsdk:"nonsensitive_data"`
+	Documents        types.List   `tfsdk:"documents"`
+	Raw              types.String `tfsdk:"raw"`
+}
+
+var decryptedFileType = types.ObjectType{AttrTypes: map[string]attr.Type{
+	"path":              types.StringType,
+	"format":            types.StringType,
+	"data":              types.MapType{ElemType: types.StringType},
+	"nonsensitive_data": types.MapType{ElemType: types.StringType},
+	"documents":         types.ListType{ElemType: types.MapType{ElemType: types.StringType}},
+	"raw":               types.StringType,
+}}
+
+func NewDataSourceFiles() datasource.DataSource {
+	return &dataSourceFiles{}
+}
+
+func (d *dataSourceFiles) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_files"
+}
+
+func (d *dataSourceFiles) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Decrypts every sops-encrypted file of a directory matching glob patterns.",
+		Attributes: withDataSourceFlattenAttributes(map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"directory": schema.StringAttribute{
+				Required: true,
+			},
+			"include": schema.ListAttribute{
+				ElementType: types.StringType,
+				Required:    true,
+				Description: "Glob patterns, relative to directory, of the files to decrypt.",
+				Validators:  []validator.List{minItemsValidator{min: 1}, globListValidator},
+			},
+			"exclude": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Glob patterns, relative to directory, of files to skip even if they match include.",
+				Validators:  []validator.List{globListValidator},
+			},
+			"input_type": schema.StringAttribute{
+				Optional: true,
+			},
+			"concurrency": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Maximum number of files decrypted at the same time. Defaults to 4.",
+			},
+			"files": schema.MapNestedAttribute{
+				Computed:    true,
+				Description: "The decrypted files, keyed by their path relative to directory.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"path": schema.StringAttribute{
+							Computed: true,
+						},
+						"format": schema.StringAttribute{
+							Computed: true,
+						},
+						"data": schema.MapAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Sensitive:   true,
+						},
+						"nonsensitive_data": schema.MapAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The values of data that sops left unencrypted.",
+						},
+						"documents": schema.ListAttribute{
+							ElementType: types.MapType{ElemType: types.StringType},
+							Computed:    true,
+							Sensitive:   true,
+						},
+						"raw": schema.StringAttribute{
+							Computed:  true,
+							Sensitive: true,
+						},
+					},
+				},
+			},
+			"raw": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Sensitive:   true,
+			},
+		}),
+		Blocks: map[string]schema.Block{
+			"ini_options": dataSourceIniOptionsBlock(),
+		},
+	}
+}
+
+// decryptedFile is the result of decrypting one of the matched files. tree
+// is its first document, nil when it has none. isCleartext reports the
+// values sops left unencrypted, and is nil for raw files.
+type decryptedFile struct {
+	path        string
+	format      string
+	cleartext   []byte
+	tree        map[string]interface{}
+	documents   []map[string]interface{}
+	isCleartext func(keys []string) bool
+	err         error
+}
+
+func (d *dataSourceFiles) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var model dataSourceFilesModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	flattenOpts, err := model.flattenOptionsModel.options()
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("list_index_format"), "Invalid list_index_format", err.Error())
+		return
+	}
+	concurrency := int64(4)
+	if !model.Concurrency.IsNull() {
+		concurrency = model.Concurrency.ValueInt64()
+	}
+	if concurrency < 1 {
+		resp.Diagnostics.AddAttributeError(path.Root("concurrency"), "Invalid concurrency", fmt.Sprintf("concurrency must be at least 1, got %d", concurrency))
+		return
+	}
+	var includePatterns, excludePatterns []string
+	resp.Diagnostics.Append(model.Include.ElementsAs(ctx, &includePatterns, false)...)
+	resp.Diagnostics.Append(model.Exclude.ElementsAs(ctx, &excludePatterns, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	include, err := compileGlobs(includePatterns)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("include"), "Invalid glob pattern", err.Error())
+		return
+	}
+	exclude, err := compileGlobs(excludePatterns)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("exclude"), "Invalid glob pattern", err.Error())
+		return
+	}
+
+	directory := model.Directory.ValueString()
+	paths, err := matchFiles(directory, include, exclude)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("directory"), "Failed to list files", err.Error())
+		return
+	}
+
+	results := make([]decryptedFile, len(paths))
+	inputType := model.InputType.ValueString()
+	iniOpts := model.IniOptions.options()
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, p := range paths {
+		wg.Add(1)
+		go func(i int, p string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = decryptFile(ctx, directory, p, inputType, iniOpts)
+		}(i, p)
+	}
+	wg.Wait()
+
+	files := make(map[string]decryptedFileModel, len(results))
+	raw := make(map[string]string, len(results))
+	var failures []string
+	for _, r := range results {
+		var data map[string]string
+		nonsensitive := map[string]string{}
+		documents := make([]map[string]string, len(r.documents))
+		if r.err == nil {
+			data, r.err = flattenWithOptions(r.tree, flattenOpts)
+		}
+		if r.err == nil && r.isCleartext != nil {
+			nonsensitive, r.err = flattenSelected(r.tree, flattenOpts, r.isCleartext)
+		}
+		for i := range r.documents {
+			if r.err == nil {
+				documents[i], r.err = flattenWithOptions(r.documents[i], flattenOpts)
+			}
+		}
+		if r.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", r.path, r.err))
+			continue
+		}
+		dataValue, diags := types.MapValueFrom(ctx, types.StringType, data)
+		resp.Diagnostics.Append(diags...)
+		nonsensitiveValue, diags := types.MapValueFrom(ctx, types.StringType, nonsensitive)
+		resp.Diagnostics.Append(diags...)
+		documentsValue, diags := types.ListValueFrom(ctx, types.MapType{ElemType: types.StringType}, documents)
+		resp.Diagnostics.Append(diags...)
+		files[r.path] = decryptedFileModel{
+			Path:             types.StringValue(r.path),
+			Format:           types.StringValue(r.format),
+			Data:             dataValue,
+			NonsensitiveData: nonsensitiveValue,
+			Documents:        documentsValue,
+			Raw:              types.StringValue(string(r.cleartext)),
+		}
+		raw[r.path] = string(r.cleartext)
+	}
+	if len(failures) > 0 {
+		resp.Diagnostics.AddError(
+			fmt.Sprintf("Failed to decrypt %d of %d file(s) in %s", len(failures), len(results), directory),
+			strings.Join(failures, "\n\n"),
+		)
+		return
+	}
+
+	filesValue, diags := types.MapValueFrom(ctx, decryptedFileType, files)
+	resp.Diagnostics.Append(diags...)
+	rawValue, diags := types.MapValueFrom(ctx, types.StringType, raw)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	model.Files = filesValue
+	model.Raw = rawValue
+	model.ID = types.StringValue(directory)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func decryptFile(ctx context.Context, directory, p, inputType string, iniOpts ini.Options) decryptedFile {
+	result := decryptedFile{path: p}
+	content, err := ioutil.ReadFile(filepath.Join(directory, filepath.FromSlash(p)))
+	if err != nil {
+		result.err = err
+		return result
+	}
+	format := inputType
+	if format == "" {
+		format = formatForPath(p)
+	}
+	result.format, result.err = resolveInputType(format, content)
+	if result.err != nil {
+		return result
+	}
+	result.cleartext, result.err = decryptData(ctx, content, result.format)
+	if result.err != nil || result.format == "raw" {
+		return result
+	}
+	result.isCleartext, result.err = cleartextSelector(content, result.format)
+	if result.err != nil {
+		return result
+	}
+	result.documents, result.err = unmarshalDocuments(result.cleartext, result.format, iniOpts)
+	if len(result.documents) > 0 {
+		result.tree = result.documents[0]
+	}
+	return result
+}
+
+func compileGlobs(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := globToRegexp(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// matchFiles walks directory and returns the sorted, slash-separated paths
+// relative to it that match an include pattern and no exclude pattern
+func matchFiles(directory string, include, exclude []*regexp.Regexp) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(directory, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(directory, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchesAny(include, rel) && !matchesAny(exclude, rel) {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
+}
+
+func matchesAny(patterns []*regexp.Regexp, p string) bool {
+	for _, re := range patterns {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const configTestDataSourceSopsFiles_basic = `
+data "sops_files" "test_basic" {
+  directory = "%s/test-fixtures"
+  include   = ["basic.*", "nested.yaml"]
+  exclude   = ["*.env"]
+}`
+
+func TestDataSourceSopsFiles_basic(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFiles_basic, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_files.test_basic", "files.%", "3"),
+					resource.TestCheckResourceAttr("data.sops_files.test_basic", "files.basic.json.path", "basic.json"),
+					resource.TestCheckResourceAttr("data.sops_files.test_basic", "files.basic.json.format", "json"),
+					resource.TestCheckResourceAttr("data.sops_files.test_basic", "files.basic.json.data.hello", "world"),
+					resource.TestCheckResourceAttr("data.sops_files.test_basic", "files.basic.yaml.data.integer", "0"),
+					resource.TestCheckResourceAttr("data.sops_files.test_basic", "files.nested.yaml.data.db.user", "foo"),
+					resource.TestCheckResourceAttrSet("data.sops_files.test_basic", "raw.nested.yaml"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsFiles_failures = `
+data "sops_files" "test_failures" {
+  directory = "%s"
+  include   = ["**/*.yaml"]
+}`
+
+func TestDataSourceSopsFiles_failures(t *testing.T) {
+	dir := t.TempDir()
+	content, err := ioutil.ReadFile("test-fixtures/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "env"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "env", "good.yaml"), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"plain.yaml", "env/plain.yaml"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("hello: world\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsFiles_failures, dir)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      config,
+				ExpectError: regexp.MustCompile(`(?s)Failed to decrypt 2 of 3 file\(s\).*env/plain\.yaml.*plain\.yaml`),
+			},
+		},
+	})
+}
+
+// testDataSourceRead reads d with the given attributes set in its
+// configuration, leaving the others null
+func testDataSourceRead(t *testing.T, d datasource.DataSource, attrs map[string]tftypes.Value) (tfsdk.State, *datasource.ReadResponse) {
+	t.Helper()
+	ctx := context.Background()
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := make(map[string]tftypes.Value, len(objType.AttributeTypes))
+	for name, typ := range objType.AttributeTypes {
+		if v, ok := attrs[name]; ok {
+			values[name] = v
+		} else {
+			values[name] = tftypes.NewValue(typ, nil)
+		}
+	}
+	config := tftypes.NewValue(objType, values)
+
+	resp := &datasource.ReadResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: config},
+	}
+	d.Read(ctx, datasource.ReadRequest{Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: config}}, resp)
+	return resp.State, resp
+}
+
+func TestDataSourceFiles_read(t *testing.T) {
+	patterns := func(p ...string) tftypes.Value {
+		values := make([]tftypes.Value, len(p))
+		for i, pattern := range p {
+			values[i] = tftypes.NewValue(tftypes.String, pattern)
+		}
+		return tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, values)
+	}
+	state, resp := testDataSourceRead(t, NewDataSourceFiles(), map[string]tftypes.Value{
+		"directory": tftypes.NewValue(tftypes.String, "test-fixtures"),
+		"include":   patterns("basic.*", "nested.yaml", "multi-document.yaml", "unencrypted-regex.yaml"),
+		"exclude":   patterns("*.env"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	var model dataSourceFilesModel
+	if diags := state.Get(context.Background(), &model); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	files := map[string]decryptedFileModel{}
+	model.Files.ElementsAs(context.Background(), &files, false)
+	if len(files) != 5 {
+		t.Fatalf("expected 5 files, got %v", files)
+	}
+	file, ok := files["nested.yaml"]
+	if !ok || file.Path.ValueString() != "nested.yaml" || file.Format.ValueString() != "yaml" {
+		t.Fatalf("expected nested.yaml to be keyed by its path, got %v", files)
+	}
+	data := map[string]string{}
+	file.Data.ElementsAs(context.Background(), &data, false)
+	if data["db.user"] != "foo" {
+		t.Errorf("unexpected data: %v", data)
+	}
+
+	nonsensitive := map[string]string{}
+	file.NonsensitiveData.ElementsAs(context.Background(), &nonsensitive, false)
+	if len(nonsensitive) != 0 {
+		t.Errorf("expected no nonsensitive data for a fully encrypted file, got %v", nonsensitive)
+	}
+	files["unencrypted-regex.yaml"].NonsensitiveData.ElementsAs(context.Background(), &nonsensitive, false)
+	expected := map[string]string{"region": "eu-west-1", "hosts.0": "a.example", "hosts.1": "b.example", "db.region": "inner"}
+	if !reflect.DeepEqual(nonsensitive, expected) {
+		t.Errorf("expected the values sops left unencrypted, got %v", nonsensitive)
+	}
+
+	var documents []map[string]string
+	files["multi-document.yaml"].Documents.ElementsAs(context.Background(), &documents, false)
+	if len(documents) != 2 || documents[0]["metadata.name"] != "db" || documents[1]["data.token"] != "abc123" {
+		t.Errorf("expected every document to be decoded, got %v", documents)
+	}
+}
@@ -0,0 +1,132 @@
+package sops
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceGitFile() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceGitFileRead,
+
+		Schema: withFlattenSchema(map[string]*schema.Schema{
+			"repository": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Path to a local git repository.",
+			},
+			"ref": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     "HEAD",
+				Description: "Branch, tag or commit SHA to read the file from.",
+			},
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Path of the encrypted file relative to the repository root.",
+			},
+			"input_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"ini_options": iniOptionsSchema(),
+
+			"commit_sha": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"data": {
+				Type:      schema.TypeMap,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"nonsensitive_data": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The values of data that sops left unencrypted.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"documents": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The flattened data of each document of a multi-document YAML file.",
+				Elem: &schema.Schema{
+					Type: schema.TypeMap,
+					Elem: &schema.Schema{
+						Type: schema.TypeString,
+					},
+				},
+			},
+			"raw": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+		}),
+	}
+}
+
+func dataSourceGitFileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	repository := d.Get("repository").(string)
+	ref := d.Get("ref").(string)
+	filePath := strings.TrimPrefix(path.Clean(d.Get("path").(string)), "/")
+
+	sha, err := gitOutput(ctx, repository, "rev-parse", "--verify", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return diag.Errorf("Could not resolve ref %q in %s: %s", ref, repository, err)
+	}
+	commit := strings.TrimSpace(string(sha))
+
+	content, err := gitOutput(ctx, repository, "cat-file", "blob", commit+":"+filePath)
+	if err != nil {
+		return diag.Errorf("Could not read %s at %s in %s: %s", filePath, commit, repository, err)
+	}
+
+	format := d.Get("input_type").(string)
+	if format == "" {
+		format = formatForPath(filePath)
+	}
+	format, err = resolveInputType(format, content)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := d.Set("commit_sha", commit); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := readData(ctx, content, format, d); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(commit + ":" + filePath)
+	return nil
+}
+
+// gitOutput runs a git command against repository and returns its stdout
+func gitOutput(ctx context.Context, repository string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repository}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
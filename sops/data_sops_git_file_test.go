@@ -0,0 +1,99 @@
+package sops
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+// testGitRepository creates a repository where basic.yaml is committed and
+// tagged as v1, then replaced by nested.yaml in a later commit. It returns
+// the repository path and the SHA of the tagged commit.
+func testGitRepository(t *testing.T) (string, string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@local"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	copyFixture := func(name string) {
+		content, err := ioutil.ReadFile(filepath.Join("test-fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(repo, "secrets.enc.yaml"), content, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	copyFixture("basic.yaml")
+	git("add", "secrets.enc.yaml")
+	git("commit", "-q", "-m", "basic")
+	git("tag", "v1")
+	sha := git("rev-parse", "HEAD")
+	copyFixture("nested.yaml")
+	git("commit", "-q", "-am", "nested")
+	// Leave an uncommitted change in the working tree, which must be ignored
+	copyFixture("raw.txt")
+	return repo, sha
+}
+
+const configTestDataSourceSopsGitFile_tag = `
+data "sops_git_file" "test_tag" {
+  repository = "%s"
+  ref        = "v1"
+  path       = "secrets.enc.yaml"
+}`
+
+func TestDataSourceSopsGitFile_tag(t *testing.T) {
+	repo, sha := testGitRepository(t)
+	config := fmt.Sprintf(configTestDataSourceSopsGitFile_tag, repo)
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_git_file.test_tag", "commit_sha", sha),
+					resource.TestCheckResourceAttr("data.sops_git_file.test_tag", "data.hello", "world"),
+					resource.TestCheckResourceAttr("data.sops_git_file.test_tag", "data.integer", "0"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsGitFile_head = `
+data "sops_git_file" "test_head" {
+  repository = "%s"
+  path       = "secrets.enc.yaml"
+}`
+
+func TestDataSourceSopsGitFile_head(t *testing.T) {
+	repo, _ := testGitRepository(t)
+	config := fmt.Sprintf(configTestDataSourceSopsGitFile_head, repo)
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_git_file.test_head", "data.db.user", "foo"),
+					resource.TestCheckResourceAttr("data.sops_git_file.test_head", "data.db.password", "bar"),
+				),
+			},
+		},
+	})
+}
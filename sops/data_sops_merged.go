@@ -0,0 +1,147 @@
+package sops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceMerged() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceMergedRead,
+
+		Schema: withFlattenSchema(map[string]*schema.Schema{
+			"files": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Description: "Encrypted files to merge, in order. Later files take precedence.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"list_strategy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "replace",
+				Description:  "How lists present in several files are merged: replace or append.",
+				ValidateFunc: validation.StringInSlice([]string{"replace", "append"}, false),
+			},
+			"ini_options": iniOptionsSchema(),
+
+			"data": {
+				Type:      schema.TypeMap,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"nonsensitive_data": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The values of data that sops left unencrypted in the file they came from.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"json": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"provenance": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		}),
+	}
+}
+
+func dataSourceMergedRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	appendLists := d.Get("list_strategy").(string) == "append"
+	iniOpts := getIniOptions(d)
+
+	var merged, origin interface{} = map[string]interface{}{}, map[string]interface{}{}
+	selectors := map[string]func(keys []string) bool{}
+	for _, f := range d.Get("files").([]interface{}) {
+		file := f.(string)
+		result := decryptFile(ctx, "", file, "", iniOpts)
+		if result.err != nil {
+			return diag.Errorf("Failed to decrypt %s: %s", file, result.err)
+		}
+		if result.format == "raw" {
+			return diag.Errorf("Failed to merge %s: raw files have no tree to merge", file)
+		}
+		if len(result.documents) > 1 {
+			return diag.Errorf("Failed to merge %s: it has %d YAML documents, only single-document files can be merged", file, len(result.documents))
+		}
+		if result.tree == nil {
+			// Empty files have nothing to merge
+			continue
+		}
+		merged, origin = mergeTree(merged, origin, normalizeTree(result.tree), file, appendLists)
+		selectors[file] = result.isCleartext
+	}
+
+	tree, ok := merged.(map[string]interface{})
+	if !ok {
+		return diag.Errorf("Failed to merge files: expected the merged data to be a map, got %T", merged)
+	}
+	encoded, err := json.Marshal(tree)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("Failed to encode merged data: %s", err))
+	}
+	flattenOpts := getFlattenOptions(d)
+	data, err := flattenWithOptions(tree, flattenOpts)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	provenanceTree, _ := origin.(map[string]interface{})
+	provenance, err := flattenWithOptions(provenanceTree, flattenOpts)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("data", data); err != nil {
+		return diag.FromErr(err)
+	}
+	nonsensitive, err := mergedNonsensitive(tree, data, provenance, selectors, flattenOpts)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("nonsensitive_data", nonsensitive); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("json", string(encoded)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("provenance", provenance); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId("-")
+	return nil
+}
+
+// mergedNonsensitive returns the values of data that sops left unencrypted in
+// the file they came from, according to provenance. sops selects the values
+// to encrypt by their map keys, which merging preserves, so the selector of
+// each file applies to the merged tree.
+func mergedNonsensitive(tree map[string]interface{}, data, provenance map[string]string, selectors map[string]func(keys []string) bool, opts flattenOptions) (map[string]string, error) {
+	nonsensitive := make(map[string]string)
+	for file, isCleartext := range selectors {
+		cleartext, err := flattenSelected(tree, opts, isCleartext)
+		if err != nil {
+			return nil, err
+		}
+		for k := range cleartext {
+			if provenance[k] == file {
+				nonsensitive[k] = data[k]
+			}
+		}
+	}
+	return nonsensitive, nil
+}
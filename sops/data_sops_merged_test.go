@@ -0,0 +1,140 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/getsops/sops/v3/pgp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const configTestDataSourceSopsMerged_basic = `
+data "sops_merged" "test_merged" {
+  files = [
+    "%[1]s/test-fixtures/basic.json",
+    "%[1]s/test-fixtures/nested.yaml",
+    "%[1]s/test-fixtures/simple-list.yaml",
+    "%[1]s/test-fixtures/override.yaml",
+  ]
+  list_strategy = "%[2]s"
+}`
+
+func TestDataSourceSopsMerged_replace(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsMerged_basic, wd, "replace")
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "data.hello", "override"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "data.integer", "0"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "data.db.user", "foo"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "data.db.password", "baz"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "data.a_list.0", "val3"),
+					resource.TestCheckNoResourceAttr("data.sops_merged.test_merged", "data.a_list.1"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "provenance.integer", wd+"/test-fixtures/basic.json"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "provenance.db.user", wd+"/test-fixtures/nested.yaml"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "provenance.db.password", wd+"/test-fixtures/override.yaml"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceSopsMerged_append(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestDataSourceSopsMerged_basic, wd, "append")
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "data.a_list.0", "val1"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "data.a_list.1", "val2"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "data.a_list.2", "val3"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "provenance.a_list.0", wd+"/test-fixtures/simple-list.yaml"),
+					resource.TestCheckResourceAttr("data.sops_merged.test_merged", "provenance.a_list.2", wd+"/test-fixtures/override.yaml"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceSopsMerged_empty(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	var files []interface{}
+	for _, name := range []string{"a.env", "b.env"} {
+		store := storeForFormat(formats.Dotenv)
+		encrypted, err := Encrypt(ctx, EncryptOpts{
+			Cipher:      aes.NewCipher(),
+			InputStore:  store,
+			OutputStore: store,
+			InputPath:   name,
+			KeyServices: LocalKeySvc(ctx),
+			KeyGroups:   []mozillasops.KeyGroup{{pgp.NewMasterKeyFromFingerprint(testPgpFingerprint)}},
+		}, []byte(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, encrypted, 0600); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, filename)
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceMerged().Schema, map[string]interface{}{"files": files})
+	if diags := dataSourceMergedRead(ctx, d, nil); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if encoded := d.Get("json").(string); encoded != "{}" {
+		t.Errorf("expected empty files to merge into an empty map, got %s", encoded)
+	}
+}
+
+func TestDataSourceSopsMerged_multiDocument(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, dataSourceMerged().Schema, map[string]interface{}{
+		"files": []interface{}{"test-fixtures/basic.yaml", "test-fixtures/multi-document.yaml"},
+	})
+	diags := dataSourceMergedRead(context.Background(), d, nil)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "2 YAML documents") {
+		t.Errorf("expected multi-document files to be refused, got %v", diags)
+	}
+}
+
+func TestDataSourceSopsMerged_nonsensitive(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, dataSourceMerged().Schema, map[string]interface{}{
+		"files": []interface{}{"test-fixtures/unencrypted-regex.yaml", "test-fixtures/nested.yaml"},
+	})
+	if diags := dataSourceMergedRead(context.Background(), d, nil); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	nonsensitive := d.Get("nonsensitive_data").(map[string]interface{})
+	if nonsensitive["region"] != "eu-west-1" || nonsensitive["db.region"] != "inner" {
+		t.Errorf("expected the values left unencrypted by unencrypted-regex.yaml, got %v", nonsensitive)
+	}
+	for _, k := range []string{"db.password", "db.user"} {
+		if _, ok := nonsensitive[k]; ok {
+			t.Errorf("expected %s, which comes from an encrypted value, to be left out, got %v", k, nonsensitive)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package sops
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceRemoteFile() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceRemoteFileRead,
+
+		Schema: withFlattenSchema(map[string]*schema.Schema{
+			"url": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+			},
+			"input_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"request_headers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "HTTP headers to send with the request, e.g. for authentication.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"ca_bundle": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "PEM-encoded certificates to trust in addition to the system roots.",
+			},
+			"insecure": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Skip TLS certificate verification.",
+			},
+			"timeout": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      30,
+				Description:  "Timeout of each request in seconds.",
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"retry_attempts": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				Description:  "Number of times to retry the request on connection errors, 429 and 5xx responses.",
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"retry_delay": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      1,
+				Description:  "Seconds to wait before the first retry, doubled after each attempt.",
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"sha256": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Description:  "Expected hex-encoded SHA-256 of the downloaded ciphertext.",
+				ValidateFunc: validation.StringMatch(sha256Pattern, "must be a hex-encoded SHA-256 checksum"),
+			},
+			"ini_options": iniOptionsSchema(),
+
+			"data": {
+				Type:      schema.TypeMap,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"nonsensitive_data": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The values of data that sops left unencrypted.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"documents": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The flattened data of each document of a multi-document YAML file.",
+				Elem: &schema.Schema{
+					Type: schema.TypeMap,
+					Elem: &schema.Schema{
+						Type: schema.TypeString,
+					},
+				},
+			},
+			"raw": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+		}),
+	}
+}
+
+func dataSourceRemoteFileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := remoteFileClient(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	sourceURL := d.Get("url").(string)
+	content, err := fetchRemoteFile(ctx, client, sourceURL, d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if expected := d.Get("sha256").(string); expected != "" {
+		checksum := sha256.Sum256(content)
+		if actual := hex.EncodeToString(checksum[:]); !strings.EqualFold(actual, expected) {
+			return diag.Errorf("Checksum mismatch for %s: expected sha256 %s, got %s", sourceURL, expected, actual)
+		}
+	}
+
+	format := d.Get("input_type").(string)
+	if format == "" {
+		format = remoteFileFormat(sourceURL)
+	}
+	format, err = resolveInputType(format, content)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := readData(ctx, content, format, d); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+// remoteFileFormat guesses the format from the extension of the URL path,
+// falling back to detecting it from the content.
+func remoteFileFormat(sourceURL string) string {
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		return "auto"
+	}
+	return formatForPath(u.Path)
+}
+
+func remoteFileClient(d *schema.ResourceData) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: d.Get("insecure").(bool),
+	}
+	if bundle := d.Get("ca_bundle").(string); bundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(bundle)) {
+			return nil, fmt.Errorf("ca_bundle does not contain any PEM-encoded certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(d.Get("timeout").(int)) * time.Second,
+	}, nil
+}
+
+func fetchRemoteFile(ctx context.Context, client *http.Client, sourceURL string, d *schema.ResourceData) ([]byte, error) {
+	attempts := d.Get("retry_attempts").(int) + 1
+	delay := time.Duration(d.Get("retry_delay").(int)) * time.Second
+	headers := d.Get("request_headers").(map[string]interface{})
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			tflog.Debug(ctx, "Retrying remote file", map[string]interface{}{"url": sourceURL, "delay": delay.String(), "error": lastErr.Error()})
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value.(string))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return body, nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("unexpected status %s", resp.Status)
+		default:
+			return nil, fmt.Errorf("Failed to fetch %s: unexpected status %s", sourceURL, resp.Status)
+		}
+	}
+	return nil, fmt.Errorf("Failed to fetch %s after %d attempt(s): %s", sourceURL, attempts, lastErr)
+}
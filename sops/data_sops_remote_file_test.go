@@ -0,0 +1,119 @@
+package sops
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func testRemoteFileServer(t *testing.T) (*httptest.Server, []byte) {
+	content, err := ioutil.ReadFile("test-fixtures/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	failures := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/basic.yaml":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write(content)
+		case "/flaky":
+			// Fail the first request of every pair to exercise retries
+			failures++
+			if failures%2 == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write(content)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, content
+}
+
+const configTestDataSourceSopsRemoteFile_basic = `
+data "sops_remote_file" "test_basic" {
+  url = "%s/basic.yaml"
+  request_headers = {
+    Authorization = "Bearer secret"
+  }
+  sha256 = "%s"
+}`
+
+func TestDataSourceSopsRemoteFile_basic(t *testing.T) {
+	server, content := testRemoteFileServer(t)
+	checksum := sha256.Sum256(content)
+	config := fmt.Sprintf(configTestDataSourceSopsRemoteFile_basic, server.URL, hex.EncodeToString(checksum[:]))
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_remote_file.test_basic", "data.hello", "world"),
+					resource.TestCheckResourceAttr("data.sops_remote_file.test_basic", "data.integer", "0"),
+					resource.TestCheckResourceAttr("data.sops_remote_file.test_basic", "data.float", "0.2"),
+					resource.TestCheckResourceAttr("data.sops_remote_file.test_basic", "data.bool", "true"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsRemoteFile_retry = `
+data "sops_remote_file" "test_retry" {
+  url            = "%s/flaky"
+  retry_attempts = 1
+  retry_delay    = 0
+}`
+
+func TestDataSourceSopsRemoteFile_retry(t *testing.T) {
+	server, _ := testRemoteFileServer(t)
+	config := fmt.Sprintf(configTestDataSourceSopsRemoteFile_retry, server.URL)
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sops_remote_file.test_retry", "data.hello", "world"),
+				),
+			},
+		},
+	})
+}
+
+const configTestDataSourceSopsRemoteFile_checksumMismatch = `
+data "sops_remote_file" "test_checksum" {
+  url = "%s/basic.yaml"
+  request_headers = {
+    Authorization = "Bearer secret"
+  }
+  sha256 = "0000000000000000000000000000000000000000000000000000000000000000"
+}`
+
+func TestDataSourceSopsRemoteFile_checksumMismatch(t *testing.T) {
+	server, _ := testRemoteFileServer(t)
+	config := fmt.Sprintf(configTestDataSourceSopsRemoteFile_checksumMismatch, server.URL)
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      config,
+				ExpectError: regexp.MustCompile("Checksum mismatch"),
+			},
+		},
+	})
+}
@@ -0,0 +1,87 @@
+package sops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+var (
+	dotenvMacLine  = regexp.MustCompile(`(?m)^sops_mac=`)
+	iniSopsSection = regexp.MustCompile(`(?m)^\[sops\]\s*$`)
+	yamlSopsKey    = regexp.MustCompile(`(?m)^sops:\s*$`)
+)
+
+// detectFormat sniffs the format of sops-encrypted content, for sources that
+// have no filename to go by.
+//
+// The checks go from the strictest to the loosest, since a JSON document is
+// also valid YAML.
+func detectFormat(content []byte) (string, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+
+	// Binary files are stored as a JSON wrapper with only a data string and
+	// the metadata, which a JSON file holding a single data string matches as
+	// well. The JSON store reads both, so it wins, and binary files need
+	// input_type = "raw".
+	if bytes.HasPrefix(trimmed, []byte("{")) && json.Valid(trimmed) {
+		return "json", nil
+	}
+	if dotenvMacLine.Match(trimmed) {
+		return "dotenv", nil
+	}
+	if iniSopsSection.Match(trimmed) {
+		return "ini", nil
+	}
+	if yamlSopsKey.Match(trimmed) {
+		return "yaml", nil
+	}
+	return "", fmt.Errorf("Could not detect the format of the sops-encrypted content, set input_type to json, yaml, ini, dotenv or raw as appropriate")
+}
+
+// resolveInputType replaces the "auto" input type with the format detected
+// from content, and validates the result
+func resolveInputType(inputType string, content []byte) (string, error) {
+	if inputType == "auto" {
+		detected, err := detectFormat(content)
+		if err != nil {
+			return "", err
+		}
+		inputType = detected
+	}
+	if err := validateInputType(inputType); err != nil {
+		return "", err
+	}
+	return inputType, nil
+}
+
+// formatForPath returns the input type matching the extension of p, or
+// "auto" when the extension is not recognised
+func formatForPath(p string) string {
+	switch path.Ext(p) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".env":
+		return "dotenv"
+	case ".ini":
+		return "ini"
+	}
+	return "auto"
+}
+
+// sourceFileFormat returns inputType, or the input type matching the
+// extension of sourceFile when it is not set
+func sourceFileFormat(sourceFile, inputType string) (string, error) {
+	if inputType != "" {
+		return inputType, nil
+	}
+	format := formatForPath(sourceFile)
+	if format == "auto" {
+		return "", fmt.Errorf("Don't know how to decode file with extension %s, set input_type to json, yaml, ini, dotenv, raw or auto as appropriate", path.Ext(sourceFile))
+	}
+	return format, nil
+}
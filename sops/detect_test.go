@@ -0,0 +1,54 @@
+package sops
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tc := []struct {
+		file     string
+		expected string
+	}{
+		{file: "test-fixtures/basic.yaml", expected: "yaml"},
+		{file: "test-fixtures/basic.json", expected: "json"},
+		{file: "test-fixtures/basic.env", expected: "dotenv"},
+		{file: "test-fixtures/nested.ini", expected: "ini"},
+		// The wrapper of binary files can't be told from a JSON file with a
+		// single data string
+		{file: "test-fixtures/raw.txt", expected: "json"},
+	}
+	for _, c := range tc {
+		t.Run(c.file, func(t *testing.T) {
+			content, err := ioutil.ReadFile(c.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			format, err := detectFormat(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != c.expected {
+				t.Errorf("Unexpected format for %s, expected %s, got %s", c.file, c.expected, format)
+			}
+		})
+	}
+}
+
+func TestDetectFormat_jsonDataString(t *testing.T) {
+	content := []byte(`{"data": "ENC[AES256_GCM,data:bm90,iv:aXY=,tag:dGFn,type:str]", "sops": {"version": "3.13.3"}}`)
+	format, err := detectFormat(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "json" {
+		t.Errorf("Expected a JSON file with a single data string to be detected as json, got %s", format)
+	}
+}
+
+func TestDetectFormat_unknown(t *testing.T) {
+	_, err := detectFormat([]byte("just some text"))
+	if err == nil {
+		t.Errorf("Expected an error for content that is not sops-encrypted")
+	}
+}
@@ -0,0 +1,226 @@
+package sops
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/kms"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// deterministicEncrypt encrypts the branches returned by load like the file
+// already at filename: when its cleartext and recipients are the same, the
+// file is returned as is, and otherwise its data key and the ciphertext of
+// its unchanged values are reused. load is called once per copy of the tree
+// needed, since encrypting modifies the tree in place. ok is false when the
+// file doesn't exist or can't be reused, for example because its recipients
+// or the settings selecting the values to encrypt changed, and the content
+// must be encrypted with opts and a new data key.
+func deterministicEncrypt(ctx context.Context, filename string, opts EncryptOpts, load func() (mozillasops.TreeBranches, error)) (content []byte, ok bool, err error) {
+	ctx = withLogSubsystem(ctx, logEncrypt)
+	existing, err := ioutil.ReadFile(filename)
+	if err != nil {
+		tflog.SubsystemDebug(ctx, logEncrypt, "No file to reuse", map[string]interface{}{"filename": filename, "error": err.Error()})
+		return nil, false, nil
+	}
+	store := GetOutputStore(filename)
+	previous, err := store.LoadEncryptedFile(existing)
+	if err != nil {
+		tflog.SubsystemDebug(ctx, logEncrypt, "Can't reuse file", map[string]interface{}{"filename": filename, "error": err.Error()})
+		return nil, false, nil
+	}
+	if !sameRecipients(previous.Metadata.KeyGroups, opts.KeyGroups) {
+		tflog.SubsystemDebug(ctx, logEncrypt, "Recipients changed, generating a new data key", map[string]interface{}{"filename": filename})
+		return nil, false, nil
+	}
+	if !sameSelection(previous.Metadata, opts) {
+		tflog.SubsystemDebug(ctx, logEncrypt, "Encrypted values selection changed, generating a new data key", map[string]interface{}{"filename": filename})
+		return nil, false, nil
+	}
+	previousCleartext, err := store.LoadEncryptedFile(existing)
+	if err != nil {
+		return nil, false, err
+	}
+	dataKey, err := common.DecryptTree(common.DecryptTreeOpts{
+		Tree:        &previousCleartext,
+		KeyServices: opts.KeyServices,
+		Cipher:      aes.NewCipher(),
+	})
+	if err != nil {
+		tflog.SubsystemDebug(ctx, logEncrypt, "Can't decrypt file to reuse", map[string]interface{}{"filename": filename, "error": err.Error()})
+		return nil, false, nil
+	}
+
+	cleartext, err := load()
+	if err != nil {
+		return nil, false, err
+	}
+	previousPlain, err := store.EmitPlainFile(previousCleartext.Branches)
+	if err != nil {
+		return nil, false, err
+	}
+	plain, err := store.EmitPlainFile(cleartext)
+	if err != nil {
+		return nil, false, err
+	}
+	if bytes.Equal(plain, previousPlain) {
+		tflog.SubsystemDebug(ctx, logEncrypt, "Content unchanged, keeping file", map[string]interface{}{"filename": filename})
+		return existing, true, nil
+	}
+
+	branches, err := load()
+	if err != nil {
+		return nil, false, err
+	}
+	for _, branch := range branches {
+		if err := ensureNoMetadata(branch); err != nil {
+			return nil, false, err
+		}
+	}
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, false, err
+	}
+	tree := mozillasops.Tree{
+		Branches: branches,
+		Metadata: previous.Metadata,
+		FilePath: path,
+	}
+	tflog.SubsystemDebug(ctx, logEncrypt, "Reusing data key", map[string]interface{}{"filename": filename})
+	err = common.EncryptTree(common.EncryptTreeOpts{
+		DataKey: dataKey,
+		Tree:    &tree,
+		Cipher:  aes.NewCipher(),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range tree.Branches {
+		if i < len(previous.Branches) {
+			tree.Branches[i] = reuseCiphertext(tree.Branches[i], cleartext[i], previous.Branches[i], previousCleartext.Branches[i]).(mozillasops.TreeBranch)
+		}
+	}
+	content, err = store.EmitEncryptedFile(tree)
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
+}
+
+// sameSelection reports whether metadata selects the values to encrypt the
+// same way as opts. Otherwise, reusing it would keep encrypting values that
+// are meant to be left in cleartext, or the reverse.
+func sameSelection(metadata mozillasops.Metadata, opts EncryptOpts) bool {
+	// sops loads files without any selection setting with its default
+	// unencrypted suffix
+	if opts.UnencryptedSuffix == "" && opts.EncryptedSuffix == "" && opts.UnencryptedRegex == "" && opts.EncryptedRegex == "" {
+		opts.UnencryptedSuffix = mozillasops.DefaultUnencryptedSuffix
+	}
+	return metadata.UnencryptedSuffix == opts.UnencryptedSuffix &&
+		metadata.EncryptedSuffix == opts.EncryptedSuffix &&
+		metadata.UnencryptedRegex == opts.UnencryptedRegex &&
+		metadata.EncryptedRegex == opts.EncryptedRegex &&
+		// The provider never sets these
+		metadata.UnencryptedCommentRegex == "" &&
+		metadata.EncryptedCommentRegex == "" &&
+		!metadata.MACOnlyEncrypted
+}
+
+// reuseCiphertext replaces the values of encrypted whose cleartext is the
+// same as in the previous file with their previous ciphertext. Values are
+// encrypted with their path as additional data, so the previous ciphertext
+// stays valid at the same path.
+func reuseCiphertext(encrypted, cleartext, previous, previousCleartext interface{}) interface{} {
+	switch typed := encrypted.(type) {
+	case mozillasops.TreeBranch:
+		clear, ok1 := cleartext.(mozillasops.TreeBranch)
+		prev, ok2 := previous.(mozillasops.TreeBranch)
+		prevClear, ok3 := previousCleartext.(mozillasops.TreeBranch)
+		if !ok1 || !ok2 || !ok3 || len(prev) != len(prevClear) {
+			return encrypted
+		}
+		for i, item := range clear {
+			if _, isComment := item.Key.(mozillasops.Comment); isComment {
+				if i < len(prevClear) && reflect.DeepEqual(item.Key, prevClear[i].Key) {
+					typed[i].Key = prev[i].Key
+				}
+				continue
+			}
+			for j, prevItem := range prevClear {
+				if prevItem.Key == item.Key {
+					typed[i].Value = reuseCiphertext(typed[i].Value, item.Value, prev[j].Value, prevItem.Value)
+					break
+				}
+			}
+		}
+		return typed
+	case []interface{}:
+		clear, ok1 := cleartext.([]interface{})
+		prev, ok2 := previous.([]interface{})
+		prevClear, ok3 := previousCleartext.([]interface{})
+		if !ok1 || !ok2 || !ok3 || len(prev) != len(prevClear) {
+			return encrypted
+		}
+		for i := range typed {
+			if i < len(prev) {
+				typed[i] = reuseCiphertext(typed[i], clear[i], prev[i], prevClear[i])
+			}
+		}
+		return typed
+	}
+	if reflect.DeepEqual(cleartext, previousCleartext) {
+		return previous
+	}
+	return encrypted
+}
+
+// sameRecipients reports whether the key groups of a file encrypt for the
+// same master keys as groups
+func sameRecipients(previous, groups []mozillasops.KeyGroup) bool {
+	if len(previous) != len(groups) {
+		return false
+	}
+	for i := range groups {
+		if recipientsKey(previous[i]) != recipientsKey(groups[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// recipientsKey identifies the master keys of group. KMS keys also depend on
+// their encryption context and AWS profile, which ToString leaves out.
+func recipientsKey(group mozillasops.KeyGroup) string {
+	keys := make([]string, len(group))
+	for i, key := range group {
+		keys[i] = fmt.Sprintf("%T:%s", key, key.ToString())
+		if kmsKey, ok := key.(*kms.MasterKey); ok {
+			keys[i] += fmt.Sprintf(";profile=%q;context=%s", kmsKey.AwsProfile, kmsContextKey(kmsKey.EncryptionContext))
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// kmsContextKey returns the KMS encryption context as sorted key=value pairs
+func kmsContextKey(context map[string]*string) string {
+	pairs := make([]string, 0, len(context))
+	for k, v := range context {
+		value := ""
+		if v != nil {
+			value = *v
+		}
+		pairs = append(pairs, fmt.Sprintf("%q=%q", k, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
@@ -0,0 +1,101 @@
+package sops
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/kms"
+	"github.com/getsops/sops/v3/pgp"
+)
+
+func TestDeterministicEncrypt(t *testing.T) {
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "secret.enc.yaml")
+	svcs := LocalKeySvc(ctx)
+	groups := []mozillasops.KeyGroup{{pgp.NewMasterKeyFromFingerprint(testPgpFingerprint)}}
+	load := func(content string) func() (mozillasops.TreeBranches, error) {
+		return func() (mozillasops.TreeBranches, error) {
+			return GetInputStore(filename).LoadPlainFile([]byte(content))
+		}
+	}
+
+	if _, ok, err := deterministicEncrypt(ctx, filename, EncryptOpts{KeyServices: svcs, KeyGroups: groups}, load("a: one\nb: two\n")); err != nil || ok {
+		t.Fatalf("expected a missing file not to be reused, got %v", err)
+	}
+	branches, _ := load("a: one\nb: two\n")()
+	initial, err := encryptBranches(ctx, EncryptOpts{
+		Cipher:      aes.NewCipher(),
+		OutputStore: GetOutputStore(filename),
+		InputPath:   filename,
+		KeyServices: LocalKeySvc(ctx),
+		KeyGroups:   groups,
+	}, branches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, initial, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	unchanged, ok, err := deterministicEncrypt(ctx, filename, EncryptOpts{KeyServices: svcs, KeyGroups: groups}, load("a: one\nb: two\n"))
+	if err != nil || !ok || string(unchanged) != string(initial) {
+		t.Fatalf("expected the file to be kept, got %v", err)
+	}
+
+	changed, ok, err := deterministicEncrypt(ctx, filename, EncryptOpts{KeyServices: svcs, KeyGroups: groups}, load("a: one\nb: three\n"))
+	if err != nil || !ok {
+		t.Fatalf("expected the file to be reused, got %v", err)
+	}
+	initialLines := strings.Split(string(initial), "\n")
+	changedLines := strings.Split(string(changed), "\n")
+	if initialLines[0] != changedLines[0] {
+		t.Errorf("expected the unchanged value to keep its ciphertext, got %q and %q", initialLines[0], changedLines[0])
+	}
+	if initialLines[1] == changedLines[1] {
+		t.Errorf("expected the changed value to be encrypted again, got %q", changedLines[1])
+	}
+	if err := ioutil.WriteFile(filename, changed, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := testCheckFileKey(filename, []string{"b"}, "three")(nil); err != nil {
+		t.Error(err)
+	}
+
+	recipients := []mozillasops.KeyGroup{append(groups[0], pgp.NewMasterKeyFromFingerprint("0000000000000000000000000000000000000000"))}
+	if _, ok, err := deterministicEncrypt(ctx, filename, EncryptOpts{KeyServices: svcs, KeyGroups: recipients}, load("a: one\nb: three\n")); err != nil || ok {
+		t.Errorf("expected new recipients to require a new data key, got %v", err)
+	}
+	for _, opts := range []EncryptOpts{
+		{KeyServices: svcs, KeyGroups: groups, EncryptedRegex: "^b$"},
+		{KeyServices: svcs, KeyGroups: groups, UnencryptedRegex: "^a$"},
+		{KeyServices: svcs, KeyGroups: groups, EncryptedSuffix: "_secret"},
+		{KeyServices: svcs, KeyGroups: groups, UnencryptedSuffix: "_clear"},
+	} {
+		if _, ok, err := deterministicEncrypt(ctx, filename, opts, load("a: one\nb: three\n")); err != nil || ok {
+			t.Errorf("expected a new selection of encrypted values %+v to require a new data key, got %v", opts, err)
+		}
+	}
+}
+
+func TestSameRecipients_kms(t *testing.T) {
+	arn := "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	group := func(profile string, context map[string]*string) []mozillasops.KeyGroup {
+		return []mozillasops.KeyGroup{{kms.NewMasterKeyWithProfile(arn, "", context, profile)}}
+	}
+	prod, staging := "prod", "staging"
+	previous := group("ops", map[string]*string{"env": &prod})
+	if !sameRecipients(previous, group("ops", map[string]*string{"env": &prod})) {
+		t.Error("expected the same KMS key, profile and context to be the same recipient")
+	}
+	if sameRecipients(previous, group("ops", map[string]*string{"env": &staging})) {
+		t.Error("expected a different encryption context to change the recipients")
+	}
+	if sameRecipients(previous, group("dev", map[string]*string{"env": &prod})) {
+		t.Error("expected a different AWS profile to change the recipients")
+	}
+}
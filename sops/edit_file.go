@@ -0,0 +1,235 @@
+package sops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// fileLocks serialises the edits of a file, since Terraform applies the
+// resources editing the same file concurrently. It only covers the resources
+// of one provider process, not concurrent runs or other tools.
+var fileLocks sync.Map
+
+func lockFile(filename string) func() {
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	lock, _ := fileLocks.LoadOrStore(filename, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
+// encryptedFile is a decrypted sops tree along with what is needed to write
+// it back encrypted with the same data key
+type encryptedFile struct {
+	tree    mozillasops.Tree
+	store   mozillasops.Store
+	dataKey []byte
+}
+
+// loadEncryptedFile reads and decrypts filename
+func loadEncryptedFile(ctx context.Context, filename, inputType string) (*encryptedFile, error) {
+	content, format, err := readSourceFile(filename, inputType)
+	if err != nil {
+		return nil, err
+	}
+	if format == "raw" {
+		return nil, fmt.Errorf("%s is a raw file, which has no keys to edit", filename)
+	}
+	store := storeForFormat(formats.FormatFromString(format))
+	tree, err := store.LoadEncryptedFile(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %s", filename, err)
+	}
+	ctx = withLogSubsystem(ctx, logDecrypt)
+	tflog.SubsystemDebug(ctx, logDecrypt, "Decrypting file for editing", map[string]interface{}{"filename": filename, "format": format})
+	dataKey, err := common.DecryptTree(common.DecryptTreeOpts{
+		Tree:        &tree,
+		KeyServices: LocalKeySvc(ctx),
+		Cipher:      aes.NewCipher(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %s", filename, err)
+	}
+	return &encryptedFile{tree: tree, store: store, dataKey: dataKey}, nil
+}
+
+// editEncryptedFile applies edit to the document at index document of
+// filename and writes it back, keeping its keys, data key and comments
+func editEncryptedFile(ctx context.Context, filename, inputType string, document int, edit func(mozillasops.TreeBranch) (mozillasops.TreeBranch, error)) error {
+	unlock := lockFile(filename)
+	defer unlock()
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	file, err := loadEncryptedFile(ctx, filename, inputType)
+	if err != nil {
+		return err
+	}
+	if document >= len(file.tree.Branches) {
+		return fmt.Errorf("%s has %d document(s), there is no document %d: %w", filename, len(file.tree.Branches), document, errKeyNotFound)
+	}
+	file.tree.Branches[document], err = edit(file.tree.Branches[document])
+	if err != nil {
+		return err
+	}
+
+	ctx = withLogSubsystem(ctx, logEncrypt)
+	tflog.SubsystemDebug(ctx, logEncrypt, "Encrypting edited file", map[string]interface{}{"filename": filename})
+	err = common.EncryptTree(common.EncryptTreeOpts{
+		DataKey: file.dataKey,
+		Tree:    &file.tree,
+		Cipher:  aes.NewCipher(),
+	})
+	if err != nil {
+		return err
+	}
+	content, err := file.store.EmitEncryptedFile(file.tree)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %s", filename, err)
+	}
+	return writeFileAtomic(filename, content, info.Mode().Perm())
+}
+
+// jsonTreeValue decodes value as JSON into a tree value, the way sops --set
+// does: objects become branches and integers stay integers
+func jsonTreeValue(value string) (interface{}, error) {
+	if !json.Valid([]byte(value)) {
+		return nil, fmt.Errorf("value is not valid JSON")
+	}
+	branches, err := storeForFormat(formats.Json).LoadPlainFile([]byte(`{"value": ` + value + `}`))
+	if err != nil {
+		return nil, err
+	}
+	return branches[0][0].Value, nil
+}
+
+// treeGet returns the value at keys in v
+func treeGet(v interface{}, keys []string) (interface{}, bool) {
+	if len(keys) == 0 {
+		return v, true
+	}
+	switch typed := v.(type) {
+	case mozillasops.TreeBranch:
+		for _, item := range typed {
+			if item.Key == keys[0] {
+				return treeGet(item.Value, keys[1:])
+			}
+		}
+	case []interface{}:
+		if i, err := strconv.Atoi(keys[0]); err == nil && i >= 0 && i < len(typed) {
+			return treeGet(typed[i], keys[1:])
+		}
+	}
+	return nil, false
+}
+
+// treeSet sets the value at keys in v, creating the missing maps. keys
+// index lists with their position, or their length to append to them.
+func treeSet(v interface{}, keys []string, value interface{}) (interface{}, error) {
+	if len(keys) == 0 {
+		return value, nil
+	}
+	switch typed := v.(type) {
+	case mozillasops.TreeBranch:
+		for i, item := range typed {
+			if item.Key == keys[0] {
+				child, err := treeSet(item.Value, keys[1:], value)
+				if err != nil {
+					return nil, err
+				}
+				typed[i].Value = child
+				return typed, nil
+			}
+		}
+		child, err := treeSet(mozillasops.TreeBranch{}, keys[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return append(typed, mozillasops.TreeItem{Key: keys[0], Value: child}), nil
+	case []interface{}:
+		i, err := strconv.Atoi(keys[0])
+		if err != nil || i < 0 || i > len(typed) {
+			return nil, fmt.Errorf("%q is not an index of a list of %d items", keys[0], len(typed))
+		}
+		if i == len(typed) {
+			typed = append(typed, mozillasops.TreeBranch{})
+		}
+		child, err := treeSet(typed[i], keys[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		typed[i] = child
+		return typed, nil
+	}
+	return nil, fmt.Errorf("can't set %q in a %T value", keys[0], v)
+}
+
+// treeUnset removes the value at keys from v, reporting whether it was found.
+// List elements other than the last one are set to null instead, so that the
+// following elements keep their index.
+func treeUnset(v interface{}, keys []string) (interface{}, bool) {
+	switch typed := v.(type) {
+	case mozillasops.TreeBranch:
+		for i, item := range typed {
+			if item.Key != keys[0] {
+				continue
+			}
+			if len(keys) == 1 {
+				return append(typed[:i], typed[i+1:]...), true
+			}
+			child, found := treeUnset(item.Value, keys[1:])
+			typed[i].Value = child
+			return typed, found
+		}
+	case []interface{}:
+		i, err := strconv.Atoi(keys[0])
+		if err != nil || i < 0 || i >= len(typed) {
+			return typed, false
+		}
+		if len(keys) == 1 {
+			if i < len(typed)-1 {
+				typed[i] = nil
+				return typed, true
+			}
+			return typed[:i], true
+		}
+		child, found := treeUnset(typed[i], keys[1:])
+		typed[i] = child
+		return typed, found
+	}
+	return v, false
+}
+
+// treePrune removes the maps left empty along keys, innermost first, such as
+// the ones treeSet created for a value that was unset. Maps that are list
+// elements other than the last one are kept, so that the following elements
+// keep their index.
+func treePrune(branch mozillasops.TreeBranch, keys []string) mozillasops.TreeBranch {
+	for depth := len(keys) - 1; depth > 0; depth-- {
+		value, _ := treeGet(branch, keys[:depth])
+		if child, ok := value.(mozillasops.TreeBranch); !ok || len(child) > 0 {
+			break
+		}
+		parent, _ := treeGet(branch, keys[:depth-1])
+		if list, ok := parent.([]interface{}); ok && keys[depth-1] != strconv.Itoa(len(list)-1) {
+			break
+		}
+		pruned, _ := treeUnset(branch, keys[:depth])
+		branch = pruned.(mozillasops.TreeBranch)
+	}
+	return branch
+}
@@ -1,28 +1,27 @@
 package sops
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"strings"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	wordwrap "github.com/mitchellh/go-wordwrap"
 
-	mozillasops "go.mozilla.org/sops/v3"
-	"go.mozilla.org/sops/v3/age"
-	"go.mozilla.org/sops/v3/logging"
-	//"go.mozilla.org/sops/v3/azkv"
-	"go.mozilla.org/sops/v3/cmd/sops/codes"
-	"go.mozilla.org/sops/v3/cmd/sops/common"
-	"go.mozilla.org/sops/v3/gcpkms"
-	//"go.mozilla.org/sops/v3/hcvault"
-	"go.mozilla.org/sops/v3/keys"
-	"go.mozilla.org/sops/v3/keyservice"
-	"go.mozilla.org/sops/v3/kms"
-	"go.mozilla.org/sops/v3/version"
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/age"
+	//"github.com/getsops/sops/v3/azkv"
+	"github.com/getsops/sops/v3/cmd/sops/codes"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/gcpkms"
+	//"github.com/getsops/sops/v3/hcvault"
+	"github.com/getsops/sops/v3/keys"
+	"github.com/getsops/sops/v3/keyservice"
+	"github.com/getsops/sops/v3/kms"
+	"github.com/getsops/sops/v3/version"
 )
 
-var log = logging.NewLogger("SOPS")
-
 type EncryptOpts struct {
 	Cipher            mozillasops.Cipher
 	InputStore        mozillasops.Store
@@ -64,14 +63,24 @@ func ensureNoMetadata(branch mozillasops.TreeBranch) error {
 	return nil
 }
 
-func Encrypt(opts EncryptOpts, fileBytes []byte) (encryptedFile []byte, err error) {
-
+func Encrypt(ctx context.Context, opts EncryptOpts, fileBytes []byte) (encryptedFile []byte, err error) {
 	branches, err := opts.InputStore.LoadPlainFile(fileBytes)
 	if err != nil {
-		return nil, common.NewExitError(fmt.Sprintf("Error unmarshalling file: %tfSops", err), codes.CouldNotReadInputFile)
+		return nil, common.NewExitError(fmt.Sprintf("Error unmarshalling file: %s", err), codes.CouldNotReadInputFile)
 	}
-	if err := ensureNoMetadata(branches[0]); err != nil {
-		return nil, common.NewExitError(err, codes.FileAlreadyEncrypted)
+	return encryptBranches(ctx, opts, branches)
+}
+
+// encryptBranches encrypts already parsed cleartext, such as content_object
+// converted to a tree
+func encryptBranches(ctx context.Context, opts EncryptOpts, branches mozillasops.TreeBranches) (encryptedFile []byte, err error) {
+	ctx = withLogSubsystem(ctx, logEncrypt)
+
+	// Each document of a multi-document YAML file is encrypted on its own
+	for _, branch := range branches {
+		if err := ensureNoMetadata(branch); err != nil {
+			return nil, common.NewExitError(err, codes.FileAlreadyEncrypted)
+		}
 	}
 	path, err := filepath.Abs(opts.InputPath)
 	if err != nil {
@@ -90,9 +99,10 @@ func Encrypt(opts EncryptOpts, fileBytes []byte) (encryptedFile []byte, err erro
 		},
 		FilePath: path,
 	}
+	tflog.SubsystemDebug(ctx, logEncrypt, "Generating data key", map[string]interface{}{"path": path, "key_groups": len(opts.KeyGroups)})
 	dataKey, errs := tree.GenerateDataKeyWithKeyServices(opts.KeyServices)
 	if len(errs) > 0 {
-		err = fmt.Errorf("Could not generate data key: %tfSops", errs)
+		err = fmt.Errorf("Could not generate data key: %v", errs)
 		return nil, err
 	}
 
@@ -107,158 +117,210 @@ func Encrypt(opts EncryptOpts, fileBytes []byte) (encryptedFile []byte, err erro
 
 	encryptedFile, err = opts.OutputStore.EmitEncryptedFile(tree)
 	if err != nil {
-		return nil, common.NewExitError(fmt.Sprintf("Could not marshal tree: %tfSops", err), codes.ErrorDumpingTree)
+		return nil, common.NewExitError(fmt.Sprintf("Could not marshal tree: %s", err), codes.ErrorDumpingTree)
 	}
 	return
 }
 
-func LocalKeySvc() (svcs []keyservice.KeyServiceClient) {
+func LocalKeySvc(ctx context.Context) (svcs []keyservice.KeyServiceClient) {
+	ctx = withLogSubsystem(ctx, logKeyservice)
+	tflog.SubsystemDebug(ctx, logKeyservice, "Using the local key service")
 	svcs = append(svcs, keyservice.NewLocalClient())
 	return
 }
 
-func GetKmsConf(d *schema.ResourceData) (KmsConf, error) {
-	conf := KmsConf{}
-	kmsConf := d.Get("kms").(map[string]interface{})
-	arn := kmsConf["arn"]
-	if arn == nil {
-		return conf, fmt.Errorf("arn is not set")
+// kmsMasterKeys returns a master key for every comma separated ARN of conf
+func kmsMasterKeys(conf KmsConf) []keys.MasterKey {
+	var encryptionContext map[string]*string
+	if len(conf.Context) > 0 {
+		encryptionContext = make(map[string]*string, len(conf.Context))
+		for k, v := range conf.Context {
+			v := v
+			encryptionContext[k] = &v
+		}
 	}
-	conf.ARN = arn.(string)
-	profile := kmsConf["profile"]
-	if profile == nil {
-		return conf, fmt.Errorf("AWS profile is not set")
+	var kmsKeys []keys.MasterKey
+	for _, k := range kms.MasterKeysFromArnString(conf.ARN, encryptionContext, conf.Profile) {
+		if conf.Role != "" {
+			k.Role = conf.Role
+		}
+		kmsKeys = append(kmsKeys, k)
 	}
-	conf.Profile = profile.(string)
-	return conf, nil
+	return kmsKeys
 }
 
-func GetAgeConf(d *schema.ResourceData) (string, error) {
-	ageConf := d.Get("age").(map[string]interface{})
-	ageKey := ageConf["key"]
-	log.Debugf("ageKey:%tfSops", ageKey)
-	if ageKey == nil {
-		return "", fmt.Errorf("age key is not set")
+// ageMasterKeys returns a master key for every age recipient
+func ageMasterKeys(recipients []string) ([]keys.MasterKey, error) {
+	var ageKeys []keys.MasterKey
+	for _, recipient := range recipients {
+		parsed, err := age.MasterKeysFromRecipients(strings.TrimSpace(recipient))
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range parsed {
+			ageKeys = append(ageKeys, k)
+		}
 	}
-	return ageKey.(string), nil
+	return ageKeys, nil
 }
 
-func GetEncryptionKey(d *schema.ResourceData, encType string) (interface{}, error) {
-	switch encType {
-	case "kms":
-		kmsConf, err := GetKmsConf(d)
-		if err != nil {
-			return nil, err
+// keyConfMasterKeys returns a master key for every key of keyConf, falling
+// back to the keys of the provider configuration when none is set
+func keyConfMasterKeys(ctx context.Context, keyConf KeyConf, config *EncryptConfig) (mozillasops.KeyGroup, error) {
+	if keyConf.isEmpty() {
+		tflog.SubsystemDebug(ctx, logEncrypt, "No key set on the resource, using the provider configuration")
+		if config.Kms.IsConfigured() {
+			kmsConf := config.Kms
+			keyConf.Kms = &kmsConf
+		}
+		keyConf.Age = config.Age
+		keyConf.GcpKms = config.GcpKms
+	}
+
+	var group mozillasops.KeyGroup
+	ageKeys, err := ageMasterKeys(keyConf.Age)
+	if err != nil {
+		return nil, err
+	}
+	group = append(group, ageKeys...)
+	if keyConf.Kms != nil && keyConf.Kms.ARN != "" {
+		group = append(group, kmsMasterKeys(*keyConf.Kms)...)
+	}
+	for _, recipients := range []struct {
+		recipientType string
+		recipients    []string
+	}{
+		{"pgp", keyConf.Pgp},
+		{"gcp_kms", keyConf.GcpKms},
+		{"azure_kv", keyConf.Azkv},
+		{"hc_vault", keyConf.Vault},
+	} {
+		for _, r := range recipients.recipients {
+			key, err := recipientMasterKey(recipients.recipientType, strings.TrimSpace(r))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s recipient %q: %s", recipients.recipientType, r, err)
+			}
+			group = append(group, key)
 		}
-		return kmsConf, nil
-	case "age":
-		ageConf, err := GetAgeConf(d)
+	}
+	if len(group) == 0 {
+		return nil, fmt.Errorf("no recipients configured, set at least one of age, pgp, kms, gcpkms, azkv or vault on the resource or the provider")
+	}
+	tflog.SubsystemDebug(ctx, logEncrypt, "Master keys available", map[string]interface{}{"keys": masterKeyStrings(group)})
+	return group, nil
+}
+
+// KeyGroups returns the key group to encrypt with. Without encType, the group
+// holds every key configured in keyConf, or in config when keyConf is empty;
+// encType selects the legacy fixed combinations instead.
+func KeyGroups(ctx context.Context, keyConf KeyConf, encType string, config *EncryptConfig) ([]mozillasops.KeyGroup, error) {
+	ctx = withLogSubsystem(ctx, logEncrypt)
+	if encType == "" {
+		group, err := keyConfMasterKeys(ctx, keyConf, config)
 		if err != nil {
 			return nil, err
 		}
-		return ageConf, nil
+		return []mozillasops.KeyGroup{group}, nil
 	}
-	return nil, fmt.Errorf("failed to recognize encType:%tfSops", encType)
-}
 
-func KeyGroups(d *schema.ResourceData, encType string, config *EncryptConfig) ([]mozillasops.KeyGroup, error) {
 	//var pgpKeys []keys.MasterKey
 	//var azkvKeys []keys.MasterKey
 	//var hcVaultMkKeys []keys.MasterKey
 	//var cloudKmsKeys []keys.MasterKey
 	var kmsKeys []keys.MasterKey
-	var ageMasterKeys []keys.MasterKey
+	var ageRecipientKeys []keys.MasterKey
 	//kmsEncryptionContext := kms.ParseKMSContext(c.String("encryption-context"))
 	//if c.String("encryption-context") != "" && kmsEncryptionContext == nil {
 	//  return nil, common.NewExitError("Invalid KMS encryption context format", codes.ErrorInvalidKMSEncryptionContextFormat)
 	//}
 	if "kms" == encType {
 
-		resourceKmsConf, err := GetKmsConf(d)
+		resourceKmsConf, err := keyConf.kmsConf()
 		if err != nil {
-			log.Errorf("fail to set kms from resource:%s", d.Id())
 			if config.Kms.IsConfigured() {
+				tflog.SubsystemDebug(ctx, logEncrypt, "No kms key set on the resource, using the provider configuration")
 				resourceKmsConf = config.Kms
 			} else {
 				return nil, err
 			}
 		}
-		//todo support encryption context
-		for _, k := range kms.MasterKeysFromArnString(resourceKmsConf.ARN, nil, resourceKmsConf.Profile) {
-			kmsKeys = append(kmsKeys, k)
-		}
+		kmsKeys = append(kmsKeys, kmsMasterKeys(resourceKmsConf)...)
 	}
 
 	if "gcpkms" == encType {
-		gcpkmsConf := d.Get("gcpkms").(map[string]interface{})
-		resourceIDs := gcpkmsConf["ids"].(string)
+		resourceIDs, err := keyConf.gcpKmsIDs()
+		if err != nil {
+			if len(config.GcpKms) == 0 {
+				return nil, err
+			}
+			tflog.SubsystemDebug(ctx, logEncrypt, "No gcpkms key set on the resource, using the provider configuration")
+			resourceIDs = config.GcpKms
+		}
 
-		for _, k := range gcpkms.MasterKeysFromResourceIDString(resourceIDs) {
-			kmsKeys = append(kmsKeys, k)
+		for _, id := range resourceIDs {
+			kmsKeys = append(kmsKeys, gcpkms.NewMasterKeyFromResourceID(strings.TrimSpace(id)))
 		}
 	}
 
 	if "age" == encType {
-		ageConf, err := GetAgeConf(d)
+		ageConf, err := keyConf.ageRecipients()
 		if err != nil {
-			log.Errorf("fail to set age key")
 			if len(config.Age) > 0 {
+				tflog.SubsystemDebug(ctx, logEncrypt, "No age recipient set on the resource, using the provider configuration")
 				ageConf = config.Age
 			} else {
 				return nil, err
 			}
 		}
-		ageKeys, err := age.MasterKeysFromRecipients(ageConf)
+		ageKeys, err := ageMasterKeys(ageConf)
 		if err != nil {
 			return nil, err
 		}
-		for _, k := range ageKeys {
-			ageMasterKeys = append(ageMasterKeys, k)
-		}
+		ageRecipientKeys = append(ageRecipientKeys, ageKeys...)
 	}
 
 	if "mix" == encType {
-		kmsConf, err := GetKmsConf(d)
+		kmsConf, err := keyConf.kmsConf()
 		if err != nil {
-			log.Errorf("fail to set kms from resource:%s\n", d.Id())
 			if config.Kms.IsConfigured() {
-				log.Infof("will use kms config from provider\n")
+				tflog.SubsystemDebug(ctx, logEncrypt, "No kms key set on the resource, using the provider configuration")
 				kmsConf = config.Kms
 			} else {
-				log.Errorf("KMS isn't configured at all.\n")
 				return nil, err
 			}
 		}
-		//todo support encryption context
-		for _, k := range kms.MasterKeysFromArnString(kmsConf.ARN, nil, kmsConf.Profile) {
-			kmsKeys = append(kmsKeys, k)
-		}
-		ageConf, err := GetAgeConf(d)
+		kmsKeys = append(kmsKeys, kmsMasterKeys(kmsConf)...)
+		ageConf, err := keyConf.ageRecipients()
 		if err != nil {
-			log.Errorf("fail to set age key")
 			if len(config.Age) > 0 {
-				log.Infof("will use age config from provider\n")
+				tflog.SubsystemDebug(ctx, logEncrypt, "No age recipient set on the resource, using the provider configuration")
 				ageConf = config.Age
 			} else {
-				log.Errorf("Age isn't configured at all.\n")
 				return nil, err
 			}
 		}
-		ageKeys, err := age.MasterKeysFromRecipients(ageConf)
+		ageKeys, err := ageMasterKeys(ageConf)
 		if err != nil {
 			return nil, err
 		}
-		for _, k := range ageKeys {
-			ageMasterKeys = append(ageMasterKeys, k)
-		}
+		ageRecipientKeys = append(ageRecipientKeys, ageKeys...)
 	}
 	var group mozillasops.KeyGroup
 	//group = append(group, azkvKeys...)
 	//group = append(group, pgpKeys...)
 	//group = append(group, hcVaultMkKeys...)
 	//group = append(group, cloudKmsKeys...)
-	group = append(group, ageMasterKeys...)
+	group = append(group, ageRecipientKeys...)
 	group = append(group, kmsKeys...)
-	log.Debugf("Master keys available:  %+v", group)
+	tflog.SubsystemDebug(ctx, logEncrypt, "Master keys available", map[string]interface{}{"keys": masterKeyStrings(group)})
 	return []mozillasops.KeyGroup{group}, nil
 }
+
+// masterKeyStrings returns the identifiers of the keys of group, for logging
+func masterKeyStrings(group mozillasops.KeyGroup) []string {
+	ids := make([]string, 0, len(group))
+	for _, k := range group {
+		ids = append(ids, k.ToString())
+	}
+	return ids
+}
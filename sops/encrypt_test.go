@@ -0,0 +1,104 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestKeyGroups_age(t *testing.T) {
+	keyConf := KeyConf{Age: []string{testAgeRecipient}}
+	groups, err := KeyGroups(context.Background(), keyConf, "age", &EncryptConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(groups) != 1 || len(groups[0]) != 1 || groups[0][0].ToString() != testAgeRecipient {
+		t.Errorf("expected a single group with the age recipient, got %v", groups)
+	}
+}
+
+func TestKeyGroups_providerFallback(t *testing.T) {
+	config := &EncryptConfig{
+		Kms: KmsConf{
+			ARN:     "arn:aws:kms:us-east-1:123456789012:key/abc",
+			Role:    "arn:aws:iam::123456789012:role/sops",
+			Context: map[string]string{"env": "prod"},
+		},
+	}
+	groups, err := KeyGroups(context.Background(), KeyConf{}, "kms", config)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(groups[0]) != 1 {
+		t.Fatalf("expected a single master key, got %v", groups[0])
+	}
+	key := groups[0][0].ToMap()
+	if key["role"] != config.Kms.Role {
+		t.Errorf("expected the role to be set, got %v", key)
+	}
+	if ctx, ok := key["context"].(map[string]string); !ok || ctx["env"] != "prod" {
+		t.Errorf("expected the encryption context to be set, got %v", key)
+	}
+}
+
+func TestKeyGroups_missingKeys(t *testing.T) {
+	for _, encType := range []string{"kms", "age", "gcpkms", "mix"} {
+		if _, err := KeyGroups(context.Background(), KeyConf{}, encType, &EncryptConfig{}); err == nil {
+			t.Errorf("expected an error for %s without keys", encType)
+		}
+	}
+}
+
+func TestKeyGroups_combined(t *testing.T) {
+	keyConf := KeyConf{
+		Age:    []string{testAgeRecipient},
+		Pgp:    []string{testPgpFingerprint},
+		Kms:    &KmsConf{ARN: "arn:aws:kms:us-east-1:123456789012:key/abc"},
+		GcpKms: []string{"projects/p/locations/global/keyRings/r/cryptoKeys/k"},
+		Azkv:   []string{"https://vault.vault.azure.net/keys/key/0123456789abcdef"},
+		Vault:  []string{"https://vault.example.com:8200/v1/transit/keys/key"},
+	}
+	groups, err := KeyGroups(context.Background(), keyConf, "", &EncryptConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(groups) != 1 || len(groups[0]) != 6 {
+		t.Fatalf("expected a single group with every key, got %v", groups)
+	}
+	var types []string
+	for _, key := range groups[0] {
+		types = append(types, fmt.Sprintf("%T", key))
+	}
+	expected := "*age.MasterKey,*kms.MasterKey,*pgp.MasterKey,*gcpkms.MasterKey,*azkv.MasterKey,*hcvault.MasterKey"
+	if got := strings.Join(types, ","); got != expected {
+		t.Errorf("expected keys %s, got %s", expected, got)
+	}
+}
+
+func TestKeyGroups_combinedProviderFallback(t *testing.T) {
+	config := &EncryptConfig{Age: []string{testAgeRecipient}}
+	groups, err := KeyGroups(context.Background(), KeyConf{}, "", config)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(groups[0]) != 1 || groups[0][0].ToString() != testAgeRecipient {
+		t.Errorf("expected the provider age recipient, got %v", groups[0])
+	}
+	if _, err := KeyGroups(context.Background(), KeyConf{}, "", &EncryptConfig{}); err == nil {
+		t.Error("expected an error without any key")
+	}
+}
+
+func TestKeyGroups_gcpKmsProviderFallback(t *testing.T) {
+	config := &EncryptConfig{GcpKms: []string{"projects/p/locations/global/keyRings/r/cryptoKeys/k"}}
+	for _, encType := range []string{"gcpkms", ""} {
+		groups, err := KeyGroups(context.Background(), KeyConf{}, encType, config)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", encType, err)
+		}
+		if len(groups[0]) != 1 || groups[0][0].ToString() != config.GcpKms[0] {
+			t.Errorf("expected the provider resource ID for %q, got %v", encType, groups[0])
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package sops
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ ephemeral.EphemeralResource = &ephemeralFile{}
+
+// ephemeralFile decrypts a file like the sops_file data source, without
+// storing the cleartext in the plan or state
+type ephemeralFile struct{}
+
+type ephemeralFileModel struct {
+	flattenOptionsModel
+	SourceFile       types.String     `tfsdk:"source_file"`
+	InputType        types.String     `tfsdk:"input_type"`
+	IniOptions       *iniOptionsModel `tfsdk:"ini_options"`
+	Data             types.Map        `tfsdk:"data"`
+	NonsensitiveData types.Map        `tfsdk:"nonsensitive_data"`
+	Documents        types.List       `tfsdk:"documents"`
+	Raw              types.String     `tfsdk:"raw"`
+}
+
+func NewEphemeralFile() ephemeral.EphemeralResource {
+	return &ephemeralFile{}
+}
+
+func (e *ephemeralFile) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_file"
+}
+
+func (e *ephemeralFile) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Decrypts a sops-encrypted file without storing the cleartext in the plan or state.",
+		Attributes: withEphemeralFlattenAttributes(map[string]schema.Attribute{
+			"source_file": schema.StringAttribute{
+				Required: true,
+			},
+			"input_type": schema.StringAttribute{
+				Optional: true,
+			},
+			"data": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"nonsensitive_data": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The values of data that sops left unencrypted.",
+			},
+			"documents": schema.ListAttribute{
+				ElementType: types.MapType{ElemType: types.StringType},
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The flattened data of each document of a multi-document YAML file.",
+			},
+			"raw": schema.StringAttribute{
+				Computed:  true,
+				Sensitive: true,
+			},
+		}),
+		Blocks: map[string]schema.Block{
+			"ini_options": ephemeralIniOptionsBlock(),
+		},
+	}
+}
+
+func (e *ephemeralFile) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var model ephemeralFileModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	flattenOpts, err := model.flattenOptionsModel.options()
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("list_index_format"), "Invalid list_index_format", err.Error())
+		return
+	}
+	content, format, err := readSourceFile(model.SourceFile.ValueString(), model.InputType.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source_file"), "Failed to read sops file", err.Error())
+		return
+	}
+	decoded, err := decodeData(ctx, content, format, model.IniOptions.options(), flattenOpts)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to decrypt sops file", err.Error())
+		return
+	}
+
+	model.Raw = types.StringValue(decoded.raw)
+	data, diags := types.MapValueFrom(ctx, types.StringType, decoded.data)
+	resp.Diagnostics.Append(diags...)
+	nonsensitive, diags := types.MapValueFrom(ctx, types.StringType, decoded.nonsensitive)
+	resp.Diagnostics.Append(diags...)
+	documents, diags := types.ListValueFrom(ctx, types.MapType{ElemType: types.StringType}, decoded.documents)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	model.Data = data
+	model.NonsensitiveData = nonsensitive
+	model.Documents = documents
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &model)...)
+}
+
+// readSourceFile reads sourceFile and resolves its input type the way the
+// sops_file data source does
+func readSourceFile(sourceFile, inputType string) ([]byte, string, error) {
+	content, err := ioutil.ReadFile(sourceFile)
+	if err != nil {
+		return nil, "", err
+	}
+	format, err := sourceFileFormat(sourceFile, inputType)
+	if err != nil {
+		return nil, "", err
+	}
+	format, err = resolveInputType(format, content)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, format, nil
+}
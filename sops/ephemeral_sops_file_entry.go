@@ -0,0 +1,133 @@
+package sops
+
+import (
+	"context"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"gopkg.in/yaml.v2"
+)
+
+var _ ephemeral.EphemeralResource = &ephemeralFileEntry{}
+
+// ephemeralFileEntry decrypts a single top-level key of a file like the
+// sops_file_entry data source, without storing the cleartext in the plan or
+// state
+type ephemeralFileEntry struct{}
+
+type ephemeralFileEntryModel struct {
+	flattenOptionsModel
+	SourceFile types.String     `tfsdk:"source_file"`
+	DataKey    types.String     `tfsdk:"data_key"`
+	InputType  types.String     `tfsdk:"input_type"`
+	AgeKeyFile types.String     `tfsdk:"age_key_file"`
+	IniOptions *iniOptionsModel `tfsdk:"ini_options"`
+	Data       types.String     `tfsdk:"data"`
+	Yaml       types.String     `tfsdk:"yaml"`
+	Map        types.Map        `tfsdk:"map"`
+	Raw        types.String     `tfsdk:"raw"`
+}
+
+func NewEphemeralFileEntry() ephemeral.EphemeralResource {
+	return &ephemeralFileEntry{}
+}
+
+func (e *ephemeralFileEntry) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_file_entry"
+}
+
+func (e *ephemeralFileEntry) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Decrypts a single key of a sops-encrypted file without storing the cleartext in the plan or state.",
+		Attributes: withEphemeralFlattenAttributes(map[string]schema.Attribute{
+			"source_file": schema.StringAttribute{
+				Required: true,
+			},
+			"data_key": schema.StringAttribute{
+				Required: true,
+			},
+			"input_type": schema.StringAttribute{
+				Optional: true,
+			},
+			"age_key_file": schema.StringAttribute{
+				Optional: true,
+			},
+			"data": schema.StringAttribute{
+				Computed:  true,
+				Sensitive: true,
+			},
+			"yaml": schema.StringAttribute{
+				Computed:  true,
+				Sensitive: true,
+			},
+			"map": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"raw": schema.StringAttribute{
+				Computed:  true,
+				Sensitive: true,
+			},
+		}),
+		Blocks: map[string]schema.Block{
+			"ini_options": ephemeralIniOptionsBlock(),
+		},
+	}
+}
+
+func (e *ephemeralFileEntry) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var model ephemeralFileEntryModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	flattenOpts, err := model.flattenOptionsModel.options()
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("list_index_format"), "Invalid list_index_format", err.Error())
+		return
+	}
+	if ageKeyFile := model.AgeKeyFile.ValueString(); ageKeyFile != "" {
+		if err := os.Setenv("SOPS_AGE_KEY_FILE", ageKeyFile); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("age_key_file"), "Failed to set SOPS_AGE_KEY_FILE", err.Error())
+			return
+		}
+	}
+	content, format, err := readSourceFile(model.SourceFile.ValueString(), model.InputType.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source_file"), "Failed to read sops file", err.Error())
+		return
+	}
+	decoded, err := decodeData(ctx, content, format, model.IniOptions.options(), flattenOpts)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to decrypt sops file", err.Error())
+		return
+	}
+
+	key := model.DataKey.ValueString()
+	entry, err := flattenFromKey(decoded.tree, key, flattenOpts)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("data_key"), "Key not found", err.Error())
+		return
+	}
+	out, err := yaml.Marshal(map[string]interface{}{key: decoded.tree[key]})
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to encode entry as YAML", err.Error())
+		return
+	}
+
+	model.Data = types.StringValue(decoded.data[newFlattener(flattenOpts).child("", key)])
+	model.Yaml = types.StringValue(string(out))
+	model.Raw = types.StringValue(decoded.raw)
+	entryMap, diags := types.MapValueFrom(ctx, types.StringType, entry)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	model.Map = entryMap
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &model)...)
+}
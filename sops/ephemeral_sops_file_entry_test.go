@@ -0,0 +1,51 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestEphemeralFileEntry_nested(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, resp := testEphemeralOpen(t, NewEphemeralFileEntry(), map[string]tftypes.Value{
+		"source_file": tftypes.NewValue(tftypes.String, fmt.Sprintf("%s/test-fixtures/nested.yaml", wd)),
+		"data_key":    tftypes.NewValue(tftypes.String, "db"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	var model ephemeralFileEntryModel
+	if diags := result.Get(context.Background(), &model); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	entry := map[string]string{}
+	model.Map.ElementsAs(context.Background(), &entry, false)
+	if entry["db.user"] != "foo" {
+		t.Errorf("unexpected map: %v", entry)
+	}
+	if expected := "db:\n  password: bar\n  user: foo\n"; model.Yaml.ValueString() != expected {
+		t.Errorf("expected yaml %q, got %q", expected, model.Yaml.ValueString())
+	}
+}
+
+func TestEphemeralFileEntry_missingKey(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp := testEphemeralOpen(t, NewEphemeralFileEntry(), map[string]tftypes.Value{
+		"source_file": tftypes.NewValue(tftypes.String, fmt.Sprintf("%s/test-fixtures/nested.yaml", wd)),
+		"data_key":    tftypes.NewValue(tftypes.String, "missing"),
+	})
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error for a missing key")
+	}
+}
@@ -0,0 +1,105 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// testEphemeralOpen opens e with the given attributes set in its
+// configuration, leaving the others null, and returns the result
+func testEphemeralOpen(t *testing.T, e ephemeral.EphemeralResource, attrs map[string]tftypes.Value) (tfsdk.EphemeralResultData, *ephemeral.OpenResponse) {
+	t.Helper()
+	ctx := context.Background()
+	var schemaResp ephemeral.SchemaResponse
+	e.Schema(ctx, ephemeral.SchemaRequest{}, &schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := make(map[string]tftypes.Value, len(objType.AttributeTypes))
+	for name, typ := range objType.AttributeTypes {
+		if v, ok := attrs[name]; ok {
+			values[name] = v
+		} else {
+			values[name] = tftypes.NewValue(typ, nil)
+		}
+	}
+	config := tftypes.NewValue(objType, values)
+
+	resp := &ephemeral.OpenResponse{
+		Result: tfsdk.EphemeralResultData{Schema: schemaResp.Schema, Raw: config},
+	}
+	e.Open(ctx, ephemeral.OpenRequest{Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: config}}, resp)
+	return resp.Result, resp
+}
+
+func TestEphemeralFile_nested(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, resp := testEphemeralOpen(t, NewEphemeralFile(), map[string]tftypes.Value{
+		"source_file": tftypes.NewValue(tftypes.String, fmt.Sprintf("%s/test-fixtures/nested.yaml", wd)),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	var model ephemeralFileModel
+	if diags := result.Get(context.Background(), &model); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	data := map[string]string{}
+	model.Data.ElementsAs(context.Background(), &data, false)
+	if data["db.user"] != "foo" || data["db.password"] != "bar" {
+		t.Errorf("unexpected data: %v", data)
+	}
+	if model.Raw.ValueString() == "" {
+		t.Error("expected raw to be set")
+	}
+}
+
+func TestEphemeralFile_unknownExtension(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp := testEphemeralOpen(t, NewEphemeralFile(), map[string]tftypes.Value{
+		"source_file": tftypes.NewValue(tftypes.String, fmt.Sprintf("%s/test-fixtures/raw.txt", wd)),
+	})
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error for a file without a known extension")
+	}
+}
+
+func TestEphemeralFile_multiDocument(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, resp := testEphemeralOpen(t, NewEphemeralFile(), map[string]tftypes.Value{
+		"source_file": tftypes.NewValue(tftypes.String, fmt.Sprintf("%s/test-fixtures/multi-document.yaml", wd)),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	var model ephemeralFileModel
+	if diags := result.Get(context.Background(), &model); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	var documents []map[string]string
+	model.Documents.ElementsAs(context.Background(), &documents, false)
+	if len(documents) != 2 || documents[0]["data.password"] != "hunter2" || documents[1]["data.token"] != "abc123" {
+		t.Errorf("unexpected documents: %v", documents)
+	}
+	data := map[string]string{}
+	model.Data.ElementsAs(context.Background(), &data, false)
+	if data["metadata.name"] != "db" {
+		t.Errorf("expected data to hold the first document, got %v", data)
+	}
+}
@@ -1,86 +1,176 @@
 package sops
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// flattenOptions controls how flatten joins nested keys
+type flattenOptions struct {
+	// Separator joins map keys, and list indexes unless BracketIndexes is set
+	Separator string
+	// BracketIndexes renders list indexes as a[0] instead of a.0
+	BracketIndexes bool
+	// EscapeKeys prefixes a backslash to separators, brackets and backslashes
+	// found in keys, so that they can't collide with nested keys
+	EscapeKeys bool
+}
+
+var defaultFlattenOptions = flattenOptions{Separator: "."}
+
+// flattenFromKey flattens the value at key k of data, prefixing the
+// flattened keys with k
+func flattenFromKey(data map[string]interface{}, k string, opts flattenOptions) (map[string]string, error) {
+	v := data[k]
+	if v == nil {
+		return make(map[string]string), fmt.Errorf("key %s not found", k)
+	}
+	f := newFlattener(opts)
+	err := f.walk(f.child("", k), fmt.Sprintf("[%q]", k), []string{k}, v)
+	return f.out, err
+}
 
 // flatten flattens the nested struct.
 //
 // All keys will be joined by dot
 // e.g. {"a": {"b":"c"}} => {"a.b":"c"}
 // or {"a": {"b":[1,2]}} => {"a.b.0":1, "a.b.1": 2}
-func flattenFromKey(data map[string]interface{}, k string) (error, map[string]string) {
-	ret := make(map[string]string)
-	v := data[k]
-	if v == nil {
-		return fmt.Errorf("key %s not found", k), ret
+//
+// Keys that collide are silently overwritten, use flattenWithOptions to
+// detect them.
+func flatten(data map[string]interface{}) map[string]string {
+	ret, _ := flattenWithOptions(data, defaultFlattenOptions)
+	return ret
+}
+
+// flattenWithOptions flattens the nested struct like flatten, joining keys as
+// described by opts. When opts aren't the defaults, it fails if two paths of
+// data flatten to the same key, e.g. {"a[0]": 1, "a": [2]} with bracket
+// indexes. With the defaults, the path sorting last wins, as it always did.
+func flattenWithOptions(data map[string]interface{}, opts flattenOptions) (map[string]string, error) {
+	f := newFlattener(opts)
+	err := f.walk("", "", nil, data)
+	return f.out, err
+}
+
+// flattenSelected flattens data like flattenWithOptions, but only keeps the
+// values for which keep returns true. keep is given the map keys leading to
+// the value, without list indexes, the way sops matches keys.
+func flattenSelected(data map[string]interface{}, opts flattenOptions, keep func(keys []string) bool) (map[string]string, error) {
+	f := newFlattener(opts)
+	f.keep = keep
+	err := f.walk("", "", nil, data)
+	return f.out, err
+}
+
+type flattener struct {
+	opts flattenOptions
+	out  map[string]string
+	// sources records the path each flattened key was produced from, to
+	// report collisions
+	sources map[string]string
+	// keep optionally filters the values added to out
+	keep func(keys []string) bool
+	// strict reports collisions as errors instead of overwriting values,
+	// once options other than the defaults are chosen
+	strict bool
+}
+
+func newFlattener(opts flattenOptions) *flattener {
+	return &flattener{
+		opts:    opts,
+		out:     make(map[string]string),
+		sources: make(map[string]string),
+		strict:  opts != defaultFlattenOptions,
 	}
+}
+
+func (f *flattener) walk(prefix, source string, keys []string, v interface{}) error {
 	switch typed := v.(type) {
 	case map[interface{}]interface{}:
-		for fk, fv := range flatten(convertMap(typed)) {
-			ret[fmt.Sprintf("%s.%s", k, fk)] = fv
-		}
+		return f.walk(prefix, source, keys, convertMap(typed))
 	case map[string]interface{}:
-		for fk, fv := range flatten(typed) {
-			ret[fmt.Sprintf("%s.%s", k, fk)] = fv
+		sortedKeys := make([]string, 0, len(typed))
+		for k := range typed {
+			sortedKeys = append(sortedKeys, k)
+		}
+		sort.Strings(sortedKeys)
+		for _, k := range sortedKeys {
+			childKeys := append(keys[:len(keys):len(keys)], k)
+			if err := f.walk(f.child(prefix, k), fmt.Sprintf("%s[%q]", source, k), childKeys, typed[k]); err != nil {
+				return err
+			}
 		}
 	case []interface{}:
-		for fk, fv := range flattenSlice(typed) {
-			ret[fmt.Sprintf("%s.%s", k, fk)] = fv
+		for idx, v := range typed {
+			if err := f.walk(f.index(prefix, idx), fmt.Sprintf("%s[%d]", source, idx), keys, v); err != nil {
+				return err
+			}
 		}
 	default:
-		ret[k] = fmt.Sprint(typed)
+		if existing, ok := f.sources[prefix]; ok && f.strict {
+			return fmt.Errorf("%s and %s both flatten to the key %q, set escape_keys or a different flatten_separator", existing, source, prefix)
+		}
+		f.sources[prefix] = source
+		if f.keep == nil || f.keep(keys) {
+			f.out[prefix] = fmt.Sprint(typed)
+		} else {
+			delete(f.out, prefix)
+		}
 	}
-	return nil, ret
+	return nil
 }
 
-func flatten(data map[string]interface{}) map[string]string {
-	ret := make(map[string]string)
-	for k, v := range data {
-		switch typed := v.(type) {
-		case map[interface{}]interface{}:
-			for fk, fv := range flatten(convertMap(typed)) {
-				ret[fmt.Sprintf("%s.%s", k, fk)] = fv
-			}
-		case map[string]interface{}:
-			for fk, fv := range flatten(typed) {
-				ret[fmt.Sprintf("%s.%s", k, fk)] = fv
-			}
-		case []interface{}:
-			for fk, fv := range flattenSlice(typed) {
-				ret[fmt.Sprintf("%s.%s", k, fk)] = fv
-			}
-		default:
-			ret[k] = fmt.Sprint(typed)
-		}
+func (f *flattener) child(prefix, k string) string {
+	if f.opts.EscapeKeys {
+		k = f.escape(k)
 	}
-	return ret
+	if prefix == "" {
+		return k
+	}
+	return prefix + f.opts.Separator + k
 }
-func flattenSlice(data []interface{}) map[string]string {
-	ret := make(map[string]string)
-	for idx, v := range data {
-		switch typed := v.(type) {
-		case map[interface{}]interface{}:
-			for fk, fv := range flatten(convertMap(typed)) {
-				ret[fmt.Sprintf("%d.%s", idx, fk)] = fv
-			}
-		case map[string]interface{}:
-			for fk, fv := range flatten(typed) {
-				ret[fmt.Sprintf("%d.%s", idx, fk)] = fv
-			}
-		case []interface{}:
-			for fk, fv := range flattenSlice(typed) {
-				ret[fmt.Sprintf("%d.%s", idx, fk)] = fv
+
+func (f *flattener) index(prefix string, idx int) string {
+	if f.opts.BracketIndexes {
+		return fmt.Sprintf("%s[%d]", prefix, idx)
+	}
+	if prefix == "" {
+		return strconv.Itoa(idx)
+	}
+	return prefix + f.opts.Separator + strconv.Itoa(idx)
+}
+
+func (f *flattener) escape(k string) string {
+	special := []string{`\`, f.opts.Separator}
+	if f.opts.BracketIndexes {
+		special = append(special, "[", "]")
+	}
+	var b strings.Builder
+	for len(k) > 0 {
+		escaped := false
+		for _, s := range special {
+			if strings.HasPrefix(k, s) {
+				b.WriteString(`\` + s)
+				k = k[len(s):]
+				escaped = true
+				break
 			}
-		default:
-			ret[fmt.Sprint(idx)] = fmt.Sprint(typed)
+		}
+		if !escaped {
+			b.WriteByte(k[0])
+			k = k[1:]
 		}
 	}
-	return ret
+	return b.String()
 }
 
 func convertMap(originalMap map[interface{}]interface{}) map[string]interface{} {
 	convertedMap := map[string]interface{}{}
 	for key, value := range originalMap {
-		convertedMap[key.(string)] = value
+		convertedMap[fmt.Sprint(key)] = value
 	}
 	return convertedMap
 }
@@ -0,0 +1,40 @@
+package sops
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// withFlattenSchema adds the arguments controlling how data is flattened,
+// which are shared by all data sources, to s
+func withFlattenSchema(s map[string]*schema.Schema) map[string]*schema.Schema {
+	s["flatten_separator"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      ".",
+		Description:  "String used to join nested keys in data.",
+		ValidateFunc: validation.NoZeroValues,
+	}
+	s["list_index_format"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      "dot",
+		Description:  "How list indexes are written in data: dot for a.0, bracket for a[0].",
+		ValidateFunc: validation.StringInSlice([]string{"dot", "bracket"}, false),
+	}
+	s["escape_keys"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "Escape separators, brackets and backslashes found in keys with a backslash.",
+	}
+	return s
+}
+
+func getFlattenOptions(d *schema.ResourceData) flattenOptions {
+	return flattenOptions{
+		Separator:      d.Get("flatten_separator").(string),
+		BracketIndexes: d.Get("list_index_format").(string) == "bracket",
+		EscapeKeys:     d.Get("escape_keys").(bool),
+	}
+}
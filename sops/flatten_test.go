@@ -82,3 +82,100 @@ func TestFlattening(t *testing.T) {
 		})
 	}
 }
+
+func TestFlatteningWithOptions(t *testing.T) {
+	input := map[string]interface{}{
+		"a.b": 1,
+		"foo": []interface{}{
+			map[string]interface{}{"a": []interface{}{2}},
+		},
+	}
+	tc := []struct {
+		name     string
+		opts     flattenOptions
+		expected map[string]string
+	}{
+		{
+			name: "custom separator",
+			opts: flattenOptions{Separator: "/"},
+			expected: map[string]string{
+				"a.b":       "1",
+				"foo/0/a/0": "2",
+			},
+		},
+		{
+			name: "bracket indexes",
+			opts: flattenOptions{Separator: "/", BracketIndexes: true},
+			expected: map[string]string{
+				"a.b":         "1",
+				"foo[0]/a[0]": "2",
+			},
+		},
+		{
+			name: "escaped keys",
+			opts: flattenOptions{Separator: ".", EscapeKeys: true},
+			expected: map[string]string{
+				`a\.b`:      "1",
+				"foo.0.a.0": "2",
+			},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			output, err := flattenWithOptions(input, c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expected, output) {
+				t.Errorf("Unexpected flattening output, expected %v, got %v", c.expected, output)
+			}
+		})
+	}
+}
+
+func TestFlatteningWithOptions_collision(t *testing.T) {
+	input := map[string]interface{}{
+		"a.b": 1,
+		"a":   map[string]interface{}{"b": 2},
+	}
+	// The default options keep overwriting colliding keys, the path sorting
+	// last winning
+	output, err := flattenWithOptions(input, defaultFlattenOptions)
+	if err != nil {
+		t.Fatalf("unexpected error with the default options: %s", err)
+	}
+	if expected := map[string]string{"a.b": "1"}; !reflect.DeepEqual(expected, output) {
+		t.Errorf("Unexpected flattening output, expected %v, got %v", expected, output)
+	}
+
+	brackets := map[string]interface{}{
+		"a[0]": 1,
+		"a":    []interface{}{2},
+	}
+	if output, err := flattenWithOptions(brackets, flattenOptions{Separator: ".", BracketIndexes: true}); err == nil {
+		t.Errorf("Expected an error for colliding keys, got %v", output)
+	}
+
+	output, err = flattenWithOptions(input, flattenOptions{Separator: ".", EscapeKeys: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{`a\.b`: "1", "a.b": "2"}
+	if !reflect.DeepEqual(expected, output) {
+		t.Errorf("Unexpected flattening output, expected %v, got %v", expected, output)
+	}
+}
+
+func TestFlattenFromKey(t *testing.T) {
+	data := map[string]interface{}{"db": map[string]interface{}{"user": "foo"}}
+	value, err := flattenFromKey(data, "db", defaultFlattenOptions)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(value) != 1 || value["db.user"] != "foo" {
+		t.Errorf("unexpected value: %v", value)
+	}
+	if _, err := flattenFromKey(data, "missing", defaultFlattenOptions); err == nil {
+		t.Error("expected a missing key to fail")
+	}
+}
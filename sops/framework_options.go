@@ -0,0 +1,134 @@
+package sops
+
+import (
+	"fmt"
+
+	dsschema "github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/mattclegg/terraform-provider-sops/sops/sops/internal/ini"
+)
+
+// iniOptionsModel maps the ini_options block of the framework schemas
+type iniOptionsModel struct {
+	NestedSections types.Bool `tfsdk:"nested_sections"`
+	TypedValues    types.Bool `tfsdk:"typed_values"`
+	AllowShadows   types.Bool `tfsdk:"allow_shadows"`
+}
+
+func (m *iniOptionsModel) options() ini.Options {
+	if m == nil {
+		return ini.Options{}
+	}
+	return ini.Options{
+		NestedSections: m.NestedSections.ValueBool(),
+		TypedValues:    m.TypedValues.ValueBool(),
+		AllowShadows:   m.AllowShadows.ValueBool(),
+	}
+}
+
+// flattenOptionsModel maps the arguments added by withFlattenSchema
+type flattenOptionsModel struct {
+	FlattenSeparator types.String `tfsdk:"flatten_separator"`
+	ListIndexFormat  types.String `tfsdk:"list_index_format"`
+	EscapeKeys       types.Bool   `tfsdk:"escape_keys"`
+}
+
+// options applies the defaults of withFlattenSchema, which ephemeral
+// resources and framework data sources can't declare in their schema
+func (m flattenOptionsModel) options() (flattenOptions, error) {
+	opts := defaultFlattenOptions
+	if sep := m.FlattenSeparator.ValueString(); sep != "" {
+		opts.Separator = sep
+	}
+	switch m.ListIndexFormat.ValueString() {
+	case "", "dot":
+	case "bracket":
+		opts.BracketIndexes = true
+	default:
+		return opts, fmt.Errorf("list_index_format must be dot or bracket, got %s", m.ListIndexFormat.ValueString())
+	}
+	opts.EscapeKeys = m.EscapeKeys.ValueBool()
+	return opts, nil
+}
+
+// ephemeralIniOptionsBlock describes the ini_options block like
+// iniOptionsSchema
+func ephemeralIniOptionsBlock() schema.Block {
+	return schema.SingleNestedBlock{
+		Description: "Controls how INI content is mapped into data.",
+		Attributes: map[string]schema.Attribute{
+			"nested_sections": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Nest sections such as [a.b] under a, instead of using a.b as the section name.",
+			},
+			"typed_values": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Convert boolean, integer and float values to their types instead of keeping strings.",
+			},
+			"allow_shadows": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Collect repeated keys into a list instead of keeping the last value.",
+			},
+		},
+	}
+}
+
+// withEphemeralFlattenAttributes adds the arguments of withFlattenSchema to
+// the attributes of an ephemeral resource
+func withEphemeralFlattenAttributes(attrs map[string]schema.Attribute) map[string]schema.Attribute {
+	attrs["flatten_separator"] = schema.StringAttribute{
+		Optional:    true,
+		Description: "String used to join nested keys in data. Defaults to \".\".",
+	}
+	attrs["list_index_format"] = schema.StringAttribute{
+		Optional:    true,
+		Description: "How list indexes are written in data: dot for a.0, bracket for a[0]. Defaults to dot.",
+	}
+	attrs["escape_keys"] = schema.BoolAttribute{
+		Optional:    true,
+		Description: "Escape separators, brackets and backslashes found in keys with a backslash.",
+	}
+	return attrs
+}
+
+// dataSourceIniOptionsBlock describes the ini_options block of framework
+// data sources like iniOptionsSchema
+func dataSourceIniOptionsBlock() dsschema.Block {
+	return dsschema.SingleNestedBlock{
+		Description: "Controls how INI content is mapped into data.",
+		Attributes: map[string]dsschema.Attribute{
+			"nested_sections": dsschema.BoolAttribute{
+				Optional:    true,
+				Description: "Nest sections such as [a.b] under a, instead of using a.b as the section name.",
+			},
+			"typed_values": dsschema.BoolAttribute{
+				Optional:    true,
+				Description: "Convert boolean, integer and float values to their types instead of keeping strings.",
+			},
+			"allow_shadows": dsschema.BoolAttribute{
+				Optional:    true,
+				Description: "Collect repeated keys into a list instead of keeping the last value.",
+			},
+		},
+	}
+}
+
+// withDataSourceFlattenAttributes adds the arguments of withFlattenSchema to
+// the attributes of a framework data source
+func withDataSourceFlattenAttributes(attrs map[string]dsschema.Attribute) map[string]dsschema.Attribute {
+	attrs["flatten_separator"] = dsschema.StringAttribute{
+		Optional:    true,
+		Description: "String used to join nested keys in data. Defaults to \".\".",
+	}
+	attrs["list_index_format"] = dsschema.StringAttribute{
+		Optional:    true,
+		Description: "How list indexes are written in data: dot for a.0, bracket for a[0]. Defaults to dot.",
+	}
+	attrs["escape_keys"] = dsschema.BoolAttribute{
+		Optional:    true,
+		Description: "Escape separators, brackets and backslashes found in keys with a backslash.",
+	}
+	return attrs
+}
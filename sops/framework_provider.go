@@ -0,0 +1,280 @@
+package sops
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-mux/tf5to6server"
+	"github.com/hashicorp/terraform-plugin-mux/tf6muxserver"
+)
+
+// frameworkProvider serves the resources and features that have moved off
+// helper/schema. It is muxed with Provider during the transition, and alone
+// declares and configures the provider schema.
+type frameworkProvider struct{}
+
+type frameworkProviderModel struct {
+	Kms    *providerKmsModel    `tfsdk:"kms"`
+	Age    *providerAgeModel    `tfsdk:"age"`
+	GcpKms *providerGcpKmsModel `tfsdk:"gcpkms"`
+}
+
+type providerKmsModel struct {
+	ARN     types.String `tfsdk:"arn"`
+	Profile types.String `tfsdk:"profile"`
+	Role    types.String `tfsdk:"role"`
+	Context types.Map    `tfsdk:"context"`
+}
+
+type providerAgeModel struct {
+	Key            types.String `tfsdk:"key"`
+	Recipients     types.List   `tfsdk:"recipients"`
+	RecipientsFile types.String `tfsdk:"recipients_file"`
+	IdentityFile   types.String `tfsdk:"identity_file"`
+}
+
+type providerGcpKmsModel struct {
+	ResourceIDs     types.List   `tfsdk:"resource_ids"`
+	Credentials     types.String `tfsdk:"credentials"`
+	CredentialsFile types.String `tfsdk:"credentials_file"`
+}
+
+var (
+	_ provider.Provider                       = &frameworkProvider{}
+	_ provider.ProviderWithFunctions          = &frameworkProvider{}
+	_ provider.ProviderWithEphemeralResources = &frameworkProvider{}
+)
+
+func NewFrameworkProvider() provider.Provider {
+	return &frameworkProvider{}
+}
+
+func (p *frameworkProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "sops"
+}
+
+func (p *frameworkProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"kms": schema.SingleNestedAttribute{
+				Optional:    true,
+				Description: providerDescriptions["kms"],
+				Attributes: map[string]schema.Attribute{
+					"arn": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["kms.arn"],
+						Validators:  []validator.String{kmsArnValidator},
+					},
+					"profile": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["kms.profile"],
+					},
+					"role": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["kms.role"],
+						Validators:  []validator.String{iamRoleArnValidator},
+					},
+					"context": schema.MapAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: providerDescriptions["kms.context"],
+					},
+				},
+			},
+			"age": schema.SingleNestedAttribute{
+				Optional:    true,
+				Description: providerDescriptions["age"],
+				Attributes: map[string]schema.Attribute{
+					"key": schema.StringAttribute{
+						Optional:           true,
+						Description:        providerDescriptions["age.key"],
+						DeprecationMessage: "Use recipients instead.",
+						Validators:         []validator.String{ageRecipientsValidator},
+					},
+					"recipients": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: providerDescriptions["age.recipients"],
+						Validators:  []validator.List{ageRecipientListValidator},
+					},
+					"recipients_file": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["age.recipients_file"],
+					},
+					"identity_file": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["age.identity_file"],
+					},
+				},
+			},
+			"gcpkms": schema.SingleNestedAttribute{
+				Optional:    true,
+				Description: providerDescriptions["gcpkms"],
+				Attributes: map[string]schema.Attribute{
+					"resource_ids": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: providerDescriptions["gcpkms.resource_ids"],
+						Validators:  []validator.List{gcpKmsResourceIDListValidator},
+					},
+					"credentials": schema.StringAttribute{
+						Optional:    true,
+						Sensitive:   true,
+						Description: providerDescriptions["gcpkms.credentials"],
+					},
+					"credentials_file": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["gcpkms.credentials_file"],
+					},
+				},
+			},
+		},
+	}
+}
+
+func (p *frameworkProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var model frameworkProviderModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	encConf := &EncryptConfig{Unknown: !req.Config.Raw.IsFullyKnown()}
+	if kms := model.Kms; kms != nil {
+		if kms.ARN.IsNull() {
+			resp.Diagnostics.AddAttributeWarning(path.Root("kms").AtName("arn"), "Incomplete kms configuration",
+				"kms is ignored without arn, so resources without their own kms keys won't fall back to it.")
+		}
+		encConf.Kms = KmsConf{
+			ARN:     kms.ARN.ValueString(),
+			Profile: kms.Profile.ValueString(),
+			Role:    kms.Role.ValueString(),
+		}
+		resp.Diagnostics.Append(kms.Context.ElementsAs(ctx, &encConf.Kms.Context, true)...)
+	}
+	if age := model.Age; age != nil {
+		recipients := age.Recipients
+		if (recipients.IsNull() || (!recipients.IsUnknown() && len(recipients.Elements()) == 0)) && age.Key.IsNull() && age.RecipientsFile.IsNull() && age.IdentityFile.IsNull() {
+			resp.Diagnostics.AddAttributeWarning(path.Root("age").AtName("recipients"), "Incomplete age configuration",
+				"age is ignored without recipients, recipients_file or identity_file, so resources without their own age recipients won't fall back to it.")
+		}
+		resp.Diagnostics.Append(recipients.ElementsAs(ctx, &encConf.Age, true)...)
+		encConf.Age = append(encConf.Age, splitList(age.Key.ValueString())...)
+		fileRecipients, diags := ageFileRecipients(path.Root("age"), age.RecipientsFile, age.IdentityFile)
+		resp.Diagnostics.Append(diags...)
+		encConf.Age = append(encConf.Age, fileRecipients...)
+	}
+	if gcpKms := model.GcpKms; gcpKms != nil {
+		resp.Diagnostics.Append(gcpKms.ResourceIDs.ElementsAs(ctx, &encConf.GcpKms, true)...)
+		if !gcpKms.Credentials.IsNull() && !gcpKms.CredentialsFile.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("gcpkms").AtName("credentials_file"), "Conflicting gcpkms credentials",
+				"Set either credentials or credentials_file, not both.")
+			return
+		}
+		// The credentials are used by the key service of the resources of
+		// this provider only, unlike GOOGLE_CREDENTIALS which would apply to
+		// every provider sharing the process
+		if !gcpKms.Credentials.IsNull() {
+			encConf.GcpCredentials = []byte(gcpKms.Credentials.ValueString())
+		} else if !gcpKms.CredentialsFile.IsNull() {
+			credentials, err := ioutil.ReadFile(gcpKms.CredentialsFile.ValueString())
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(path.Root("gcpkms").AtName("credentials_file"), "Failed to read the GCP credentials", err.Error())
+				return
+			}
+			encConf.GcpCredentials = credentials
+		} else if gcpKms.ResourceIDs.IsNull() {
+			resp.Diagnostics.AddAttributeWarning(path.Root("gcpkms").AtName("resource_ids"), "Incomplete gcpkms configuration",
+				"gcpkms is ignored without resource_ids or credentials.")
+		}
+	}
+	tflog.Debug(ctx, "Configured provider", map[string]interface{}{
+		"kms_configured":      encConf.Kms.IsConfigured(),
+		"age_recipients":      len(encConf.Age),
+		"gcpkms_resource_ids": len(encConf.GcpKms),
+		"unknown":             encConf.Unknown,
+	})
+
+	resp.ResourceData = encConf
+}
+
+func (p *frameworkProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		NewDataSourceFiles,
+	}
+}
+
+func (p *frameworkProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		NewResourceFile,
+		NewResourceDecryptedFile,
+		NewResourceFileKey,
+	}
+}
+
+func (p *frameworkProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		NewEphemeralFile,
+		NewEphemeralFileEntry,
+	}
+}
+
+func (p *frameworkProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		NewDecryptFunction,
+		NewDecryptFileFunction,
+	}
+}
+
+// ProviderServerFactory muxes the SDK and framework providers into a single
+// protocol 6 server
+func ProviderServerFactory(ctx context.Context) (func() tfprotov6.ProviderServer, error) {
+	sdkServer, err := tf5to6server.UpgradeServer(ctx, Provider().GRPCProvider)
+	if err != nil {
+		return nil, err
+	}
+	muxServer, err := tf6muxserver.NewMuxServer(ctx,
+		providerserver.NewProtocol6(NewFrameworkProvider()),
+		func() tfprotov6.ProviderServer { return sdkProviderServer{sdkServer} },
+	)
+	if err != nil {
+		return nil, err
+	}
+	return muxServer.ProviderServer, nil
+}
+
+// sdkProviderServer hides the provider configuration from the SDK server:
+// helper/schema can't decode the kms, age and gcpkms objects, and the SDK data
+// sources don't use the provider configuration. The mux then takes the
+// provider schema from the framework provider alone.
+type sdkProviderServer struct {
+	tfprotov6.ProviderServer
+}
+
+func (s sdkProviderServer) GetProviderSchema(ctx context.Context, req *tfprotov6.GetProviderSchemaRequest) (*tfprotov6.GetProviderSchemaResponse, error) {
+	resp, err := s.ProviderServer.GetProviderSchema(ctx, req)
+	if resp != nil {
+		resp.Provider = nil
+	}
+	return resp, err
+}
+
+func (s sdkProviderServer) ValidateProviderConfig(ctx context.Context, req *tfprotov6.ValidateProviderConfigRequest) (*tfprotov6.ValidateProviderConfigResponse, error) {
+	return &tfprotov6.ValidateProviderConfigResponse{PreparedConfig: req.Config}, nil
+}
+
+func (s sdkProviderServer) ConfigureProvider(ctx context.Context, req *tfprotov6.ConfigureProviderRequest) (*tfprotov6.ConfigureProviderResponse, error) {
+	return &tfprotov6.ConfigureProviderResponse{}, nil
+}
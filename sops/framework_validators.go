@@ -0,0 +1,178 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// stringValidator adapts one of the validation functions of validate.go to
+// the framework
+type stringValidator struct {
+	description string
+	validate    func(string) error
+}
+
+var _ validator.String = stringValidator{}
+
+func (v stringValidator) Description(ctx context.Context) string {
+	return v.description
+}
+
+func (v stringValidator) MarkdownDescription(ctx context.Context) string {
+	return v.description
+}
+
+func (v stringValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if err := v.validate(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid value", err.Error())
+	}
+}
+
+// stringListValidator applies a validation function to every known element
+// of a list of strings
+type stringListValidator struct {
+	description string
+	validate    func(string) error
+}
+
+var _ validator.List = stringListValidator{}
+
+func (v stringListValidator) Description(ctx context.Context) string {
+	return "every element " + v.description
+}
+
+func (v stringListValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringListValidator) ValidateList(ctx context.Context, req validator.ListRequest, resp *validator.ListResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	for i, elem := range req.ConfigValue.Elements() {
+		s, ok := elem.(types.String)
+		if !ok || s.IsNull() || s.IsUnknown() {
+			continue
+		}
+		if err := v.validate(s.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(req.Path.AtListIndex(i), "Invalid value", err.Error())
+		}
+	}
+}
+
+// minItemsValidator requires a list to have at least min elements
+type minItemsValidator struct {
+	min int
+}
+
+var _ validator.List = minItemsValidator{}
+
+func (v minItemsValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("must have at least %d element(s)", v.min)
+}
+
+func (v minItemsValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v minItemsValidator) ValidateList(ctx context.Context, req validator.ListRequest, resp *validator.ListResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if n := len(req.ConfigValue.Elements()); n < v.min {
+		resp.Diagnostics.AddAttributeError(req.Path, "Too few elements", fmt.Sprintf("%s %s, got %d", req.Path, v.Description(ctx), n))
+	}
+}
+
+// int64AtLeastValidator requires a number to be at least min
+type int64AtLeastValidator struct {
+	min int64
+}
+
+var _ validator.Int64 = int64AtLeastValidator{}
+
+func (v int64AtLeastValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("must be at least %d", v.min)
+}
+
+func (v int64AtLeastValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v int64AtLeastValidator) ValidateInt64(ctx context.Context, req validator.Int64Request, resp *validator.Int64Response) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if n := req.ConfigValue.ValueInt64(); n < v.min {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid value", fmt.Sprintf("%s %s, got %d", req.Path, v.Description(ctx), n))
+	}
+}
+
+var (
+	kmsArnValidator = stringValidator{
+		description: "must be the ARN of a KMS key",
+		validate:    commaSeparated(validateKmsArn),
+	}
+	iamRoleArnValidator = stringValidator{
+		description: "must be the ARN of an IAM role",
+		validate:    validateIamRoleArn,
+	}
+	ageRecipientsValidator = stringValidator{
+		description: "must be a comma separated list of age or SSH public keys",
+		validate:    commaSeparated(validateAgeRecipient),
+	}
+	ageRecipientListValidator = stringListValidator{
+		description: "must be an age or SSH public key",
+		validate:    validateAgeRecipient,
+	}
+	gcpKmsResourceIDsValidator = stringValidator{
+		description: "must be a comma separated list of GCP KMS key resource IDs",
+		validate:    commaSeparated(validateGcpKmsResourceID),
+	}
+	gcpKmsResourceIDListValidator = stringListValidator{
+		description: "must be a GCP KMS key resource ID",
+		validate:    validateGcpKmsResourceID,
+	}
+	pgpFingerprintListValidator = stringListValidator{
+		description: "must be a PGP key fingerprint",
+		validate:    validatePgpFingerprint,
+	}
+	azkvURLListValidator = stringListValidator{
+		description: "must be an Azure Key Vault key URL",
+		validate:    validateAzkvURL,
+	}
+	vaultURIListValidator = stringListValidator{
+		description: "must be a Vault transit key URI",
+		validate:    validateVaultURI,
+	}
+	encryptionTypeValidator = stringValidator{
+		description: "must be one of kms, gcpkms, age or mix",
+		validate:    validateEncryptionType,
+	}
+	valueTypeValidator = stringValidator{
+		description: "must be string or json",
+		validate:    validateValueType,
+	}
+	regexValidator = stringValidator{
+		description: "must be a valid regular expression",
+		validate:    validateRegex,
+	}
+	outputTypeValidator = stringValidator{
+		description: "must be json, yaml, dotenv, ini or raw",
+		validate:    validateOutputType,
+	}
+	globListValidator = stringListValidator{
+		description: "must be a glob pattern",
+		validate:    validateGlob,
+	}
+	fileModeValidator = stringValidator{
+		description: "must be three or four octal digits",
+		validate:    validateMode,
+	}
+)
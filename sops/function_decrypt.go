@@ -0,0 +1,87 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/mattclegg/terraform-provider-sops/sops/sops/internal/ini"
+)
+
+var _ function.Function = &decryptFunction{}
+
+type decryptFunction struct{}
+
+func NewDecryptFunction() function.Function {
+	return &decryptFunction{}
+}
+
+func (f *decryptFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "decrypt"
+}
+
+func (f *decryptFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Decrypt sops-encrypted content",
+		Description: "Decrypts sops-encrypted content and returns its decoded value, or the cleartext string for the raw format.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "content",
+				Description: "The sops-encrypted content.",
+			},
+			function.StringParameter{
+				Name:        "format",
+				Description: "The format of content: json, yaml, dotenv, ini, raw or auto to detect it.",
+			},
+		},
+		Return: function.DynamicReturn{},
+	}
+}
+
+func (f *decryptFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var content, format string
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &content, &format))
+	if resp.Error != nil {
+		return
+	}
+
+	format, err := resolveInputType(format, []byte(content))
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, err.Error())
+		return
+	}
+	value, err := decryptValue(ctx, []byte(content), format)
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, types.DynamicValue(value))
+}
+
+// decryptValue decrypts content of the given, already resolved, format into
+// the value returned by the decrypt functions
+func decryptValue(ctx context.Context, content []byte, format string) (attr.Value, error) {
+	cleartext, err := decryptData(ctx, content, format)
+	if err != nil {
+		return nil, err
+	}
+	if format == "raw" {
+		return types.StringValue(string(cleartext)), nil
+	}
+	documents, err := unmarshalDocuments(cleartext, format, ini.Options{})
+	if err != nil {
+		return nil, err
+	}
+	switch len(documents) {
+	case 0:
+		return treeValue(ctx, map[string]interface{}{})
+	case 1:
+		return treeValue(ctx, documents[0])
+	}
+	// A tuple of documents would change the type of the result with the
+	// content, so multi-document files are refused instead of truncated
+	return nil, fmt.Errorf("the content has %d YAML documents, only single-document content can be decrypted", len(documents))
+}
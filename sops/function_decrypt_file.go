@@ -0,0 +1,60 @@
+package sops
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ function.Function = &decryptFileFunction{}
+
+type decryptFileFunction struct{}
+
+func NewDecryptFileFunction() function.Function {
+	return &decryptFileFunction{}
+}
+
+func (f *decryptFileFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "decrypt_file"
+}
+
+func (f *decryptFileFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Decrypt a sops-encrypted file",
+		Description: "Reads and decrypts a sops-encrypted file, guessing its format from the extension or, failing that, from its content.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "path",
+				Description: "The path of the sops-encrypted file.",
+			},
+		},
+		Return: function.DynamicReturn{},
+	}
+}
+
+func (f *decryptFileFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var path string
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &path))
+	if resp.Error != nil {
+		return
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	format, err := resolveInputType(formatForPath(path), content)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	value, err := decryptValue(ctx, content, format)
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, types.DynamicValue(value))
+}
@@ -0,0 +1,72 @@
+package sops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const configTestFunctionDecryptFile_nested = `
+output "user" {
+  value = provider::sops::decrypt_file("%s/test-fixtures/nested.yaml").db.user
+}
+
+output "raw" {
+  value = provider::sops::decrypt_file("%s/test-fixtures/raw.txt")
+}`
+
+func TestFunctionDecryptFile_nested(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestFunctionDecryptFile_nested, wd, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckOutput("user", "foo"),
+					resource.TestCheckOutput("raw", "Hello raw world!"),
+				),
+			},
+		},
+	})
+}
+
+const configTestFunctionDecryptFile_resourceArgument = `
+resource "sops_file" "copy" {
+  filename = "%s"
+  content  = yamlencode(provider::sops::decrypt_file("%s/test-fixtures/basic.yaml"))
+  pgp = {
+    fingerprints = ["%s"]
+  }
+}`
+
+// The functions must return the same result during plan and apply to be
+// passed to resource arguments
+func TestFunctionDecryptFile_resourceArgument(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "copy.enc.yaml")
+	config := fmt.Sprintf(configTestFunctionDecryptFile_resourceArgument, filename, wd, testPgpFingerprint)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check:  testCheckFileKey(filename, []string{"hello"}, "world"),
+			},
+			{
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
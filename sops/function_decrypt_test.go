@@ -0,0 +1,51 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const configTestFunctionDecrypt_basic = `
+output "hello" {
+  value = provider::sops::decrypt(file("%s/test-fixtures/basic.json"), "auto").hello
+}
+
+output "integer" {
+  value = provider::sops::decrypt(file("%s/test-fixtures/basic.json"), "json").integer
+}`
+
+func TestFunctionDecrypt_basic(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(configTestFunctionDecrypt_basic, wd, wd)
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckOutput("hello", "world"),
+					resource.TestCheckOutput("integer", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestDecryptValue_multiDocument(t *testing.T) {
+	content, err := ioutil.ReadFile("test-fixtures/multi-document.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptValue(context.Background(), content, "yaml"); err == nil || !strings.Contains(err.Error(), "2 YAML documents") {
+		t.Errorf("expected multi-document content to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// treeValue converts a decoded tree into a Terraform value, keeping the types
+// of its leaves: maps become objects, lists become tuples and null leaves
+// become null strings.
+func treeValue(ctx context.Context, v interface{}) (attr.Value, error) {
+	switch typed := v.(type) {
+	case nil:
+		return types.StringNull(), nil
+	case map[interface{}]interface{}:
+		return treeValue(ctx, convertMap(typed))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for k := range typed {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		attrTypes := make(map[string]attr.Type, len(typed))
+		attrs := make(map[string]attr.Value, len(typed))
+		for _, k := range keys {
+			value, err := treeValue(ctx, typed[k])
+			if err != nil {
+				return nil, err
+			}
+			attrTypes[k] = value.Type(ctx)
+			attrs[k] = value
+		}
+		obj, diags := types.ObjectValue(attrTypes, attrs)
+		if diags.HasError() {
+			return nil, fmt.Errorf("%s: %s", diags[0].Summary(), diags[0].Detail())
+		}
+		return obj, nil
+	case []interface{}:
+		elemTypes := make([]attr.Type, len(typed))
+		elems := make([]attr.Value, len(typed))
+		for i, v := range typed {
+			value, err := treeValue(ctx, v)
+			if err != nil {
+				return nil, err
+			}
+			elemTypes[i] = value.Type(ctx)
+			elems[i] = value
+		}
+		tuple, diags := types.TupleValue(elemTypes, elems)
+		if diags.HasError() {
+			return nil, fmt.Errorf("%s: %s", diags[0].Summary(), diags[0].Detail())
+		}
+		return tuple, nil
+	case string:
+		return types.StringValue(typed), nil
+	case bool:
+		return types.BoolValue(typed), nil
+	case int:
+		return types.NumberValue(new(big.Float).SetInt64(int64(typed))), nil
+	case int64:
+		return types.NumberValue(new(big.Float).SetInt64(typed)), nil
+	case uint64:
+		return types.NumberValue(new(big.Float).SetUint64(typed)), nil
+	case float64:
+		return types.NumberValue(big.NewFloat(typed)), nil
+	default:
+		return types.StringValue(fmt.Sprint(typed)), nil
+	}
+}
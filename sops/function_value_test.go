@@ -0,0 +1,59 @@
+package sops
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestTreeValue(t *testing.T) {
+	ctx := context.Background()
+	tree := map[string]interface{}{
+		"name":    "db",
+		"port":    5432,
+		"ratio":   0.5,
+		"enabled": true,
+		"unset":   nil,
+		"hosts":   []interface{}{"a", 1},
+		"nested":  map[interface{}]interface{}{"key": "value"},
+	}
+
+	value, err := treeValue(ctx, tree)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	obj, ok := value.(types.Object)
+	if !ok {
+		t.Fatalf("expected an object, got %T", value)
+	}
+	attrs := obj.Attributes()
+
+	expected := map[string]attr.Value{
+		"name":    types.StringValue("db"),
+		"port":    types.NumberValue(big.NewFloat(5432)),
+		"ratio":   types.NumberValue(big.NewFloat(0.5)),
+		"enabled": types.BoolValue(true),
+		"unset":   types.StringNull(),
+	}
+	for k, v := range expected {
+		if !attrs[k].Equal(v) {
+			t.Errorf("%s: expected %s, got %s", k, v, attrs[k])
+		}
+	}
+
+	hosts, ok := attrs["hosts"].(types.Tuple)
+	if !ok || len(hosts.Elements()) != 2 {
+		t.Fatalf("expected hosts to be a tuple of 2 elements, got %s", attrs["hosts"])
+	}
+	if !hosts.Elements()[1].Equal(types.NumberValue(big.NewFloat(1))) {
+		t.Errorf("expected hosts[1] to be the number 1, got %s", hosts.Elements()[1])
+	}
+
+	nested, ok := attrs["nested"].(types.Object)
+	if !ok || !nested.Attributes()["key"].Equal(types.StringValue("value")) {
+		t.Errorf("expected nested.key to be \"value\", got %s", attrs["nested"])
+	}
+}
@@ -0,0 +1,61 @@
+package sops
+
+import (
+	"context"
+
+	"github.com/getsops/sops/v3/gcpkms"
+	"github.com/getsops/sops/v3/keyservice"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// gcpCredentialsKeyService is the local key service, except that GCP KMS
+// keys are accessed with credentials instead of the ones of the environment.
+// The key service builds a new master key from the resource ID of every
+// request, so the credentials can't be set on the keys of the key groups.
+type gcpCredentialsKeyService struct {
+	keyservice.Server
+	credentials gcpkms.CredentialJSON
+}
+
+func (ks gcpCredentialsKeyService) masterKey(key *keyservice.GcpKmsKey) *gcpkms.MasterKey {
+	masterKey := gcpkms.NewMasterKeyFromResourceID(key.ResourceId)
+	ks.credentials.ApplyToMasterKey(masterKey)
+	return masterKey
+}
+
+func (ks gcpCredentialsKeyService) Encrypt(ctx context.Context, req *keyservice.EncryptRequest) (*keyservice.EncryptResponse, error) {
+	key := req.Key.GetGcpKmsKey()
+	if key == nil {
+		return ks.Server.Encrypt(ctx, req)
+	}
+	masterKey := ks.masterKey(key)
+	if err := masterKey.Encrypt(req.Plaintext); err != nil {
+		return nil, err
+	}
+	return &keyservice.EncryptResponse{Ciphertext: []byte(masterKey.EncryptedKey)}, nil
+}
+
+func (ks gcpCredentialsKeyService) Decrypt(ctx context.Context, req *keyservice.DecryptRequest) (*keyservice.DecryptResponse, error) {
+	key := req.Key.GetGcpKmsKey()
+	if key == nil {
+		return ks.Server.Decrypt(ctx, req)
+	}
+	masterKey := ks.masterKey(key)
+	masterKey.EncryptedKey = string(req.Ciphertext)
+	plaintext, err := masterKey.Decrypt()
+	if err != nil {
+		return nil, err
+	}
+	return &keyservice.DecryptResponse{Plaintext: plaintext}, nil
+}
+
+// configKeySvc returns the key services for the provider configuration: the
+// local key service, using the GCP credentials of config when they are set
+func configKeySvc(ctx context.Context, config *EncryptConfig) []keyservice.KeyServiceClient {
+	if config == nil || len(config.GcpCredentials) == 0 {
+		return LocalKeySvc(ctx)
+	}
+	ctx = withLogSubsystem(ctx, logKeyservice)
+	tflog.SubsystemDebug(ctx, logKeyservice, "Using the local key service with the provider GCP credentials")
+	return []keyservice.KeyServiceClient{keyservice.NewCustomLocalClient(gcpCredentialsKeyService{credentials: config.GcpCredentials})}
+}
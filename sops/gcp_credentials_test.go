@@ -0,0 +1,33 @@
+package sops
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/pgp"
+)
+
+func TestConfigKeySvc(t *testing.T) {
+	ctx := context.Background()
+	svcs := configKeySvc(ctx, &EncryptConfig{GcpCredentials: []byte(`{"type": "service_account"}`)})
+	if len(svcs) != 1 {
+		t.Fatalf("expected a single key service, got %d", len(svcs))
+	}
+
+	// Keys other than GCP KMS keys are left to the local key service
+	metadata := mozillasops.Metadata{KeyGroups: []mozillasops.KeyGroup{{pgp.NewMasterKeyFromFingerprint(testPgpFingerprint)}}}
+	dataKey := bytes.Repeat([]byte{1}, 32)
+	if errs := metadata.UpdateMasterKeysWithKeyServices(dataKey, svcs); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	metadata.DataKey = nil
+	decrypted, err := metadata.GetDataKeyWithKeyServices(svcs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, dataKey) {
+		t.Error("expected the data key to be decrypted")
+	}
+}
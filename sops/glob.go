@@ -0,0 +1,57 @@
+package sops
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// globToRegexp compiles a slash-separated glob pattern into a regular
+// expression.
+//
+// `*` and `?` do not match across directories, `**` does, and a `**/` prefix
+// also matches no directory at all, e.g. "**/*.enc.yaml" matches both
+// "a.enc.yaml" and "env/prod/a.enc.yaml".
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class in pattern %q", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// validateGlob ensures that pattern is a glob pattern globToRegexp compiles
+func validateGlob(pattern string) error {
+	_, err := globToRegexp(pattern)
+	return err
+}
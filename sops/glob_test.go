@@ -0,0 +1,40 @@
+package sops
+
+import "testing"
+
+func TestGlobToRegexp(t *testing.T) {
+	tc := []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{pattern: "*.enc.yaml", path: "a.enc.yaml", matches: true},
+		{pattern: "*.enc.yaml", path: "env/a.enc.yaml", matches: false},
+		{pattern: "**/*.enc.yaml", path: "a.enc.yaml", matches: true},
+		{pattern: "**/*.enc.yaml", path: "env/prod/a.enc.yaml", matches: true},
+		{pattern: "env/**", path: "env/prod/a.enc.yaml", matches: true},
+		{pattern: "env/**", path: "other/a.enc.yaml", matches: false},
+		{pattern: "env/?.json", path: "env/a.json", matches: true},
+		{pattern: "env/?.json", path: "env/ab.json", matches: false},
+		{pattern: "[!b]*.json", path: "a.json", matches: true},
+		{pattern: "[!b]*.json", path: "b.json", matches: false},
+		{pattern: "a+b.json", path: "a+b.json", matches: true},
+	}
+	for _, c := range tc {
+		t.Run(c.pattern+" "+c.path, func(t *testing.T) {
+			re, err := globToRegexp(c.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if re.MatchString(c.path) != c.matches {
+				t.Errorf("Expected %q matching %q to be %t", c.pattern, c.path, c.matches)
+			}
+		})
+	}
+}
+
+func TestGlobToRegexp_bad(t *testing.T) {
+	if _, err := globToRegexp("[abc"); err == nil {
+		t.Errorf("Expected an error for an unterminated character class")
+	}
+}
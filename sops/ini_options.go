@@ -0,0 +1,52 @@
+package sops
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/mattclegg/terraform-provider-sops/sops/sops/internal/ini"
+)
+
+// iniOptionsSchema describes the ini_options block shared by the data sources.
+func iniOptionsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "Controls how INI content is mapped into data.",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"nested_sections": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     false,
+					Description: "Nest sections such as [a.b] under a, instead of using a.b as the section name.",
+				},
+				"typed_values": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     false,
+					Description: "Convert boolean, integer and float values to their types instead of keeping strings.",
+				},
+				"allow_shadows": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     false,
+					Description: "Collect repeated keys into a list instead of keeping the last value.",
+				},
+			},
+		},
+	}
+}
+
+func getIniOptions(d *schema.ResourceData) ini.Options {
+	opts := ini.Options{}
+	blocks := d.Get("ini_options").([]interface{})
+	if len(blocks) == 0 || blocks[0] == nil {
+		return opts
+	}
+	block := blocks[0].(map[string]interface{})
+	opts.NestedSections = block["nested_sections"].(bool)
+	opts.TypedValues = block["typed_values"].(bool)
+	opts.AllowShadows = block["allow_shadows"].(bool)
+	return opts
+}
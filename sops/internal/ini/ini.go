@@ -1,11 +1,32 @@
 package ini
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"gopkg.in/ini.v1"
 )
 
+// Options controls how an INI document is mapped onto a tree.
+type Options struct {
+	// NestedSections places the keys of a section such as [a.b] under the
+	// "b" entry of the "a" map, instead of under a flat "a.b" entry.
+	NestedSections bool
+	// TypedValues converts values that look like booleans, integers or
+	// floats to the matching type, the same way YAML does.
+	TypedValues bool
+	// AllowShadows collects repeated keys into a list instead of keeping
+	// only the last value.
+	AllowShadows bool
+}
+
 func Unmarshal(in []byte, out *map[string]interface{}) error {
-	f, err := ini.Load(in)
+	return UnmarshalWithOptions(in, out, Options{})
+}
+
+func UnmarshalWithOptions(in []byte, out *map[string]interface{}, opts Options) error {
+	f, err := ini.LoadSources(ini.LoadOptions{AllowShadows: opts.AllowShadows}, in)
 	if err != nil {
 		return err
 	}
@@ -20,15 +41,75 @@ func Unmarshal(in []byte, out *map[string]interface{}) error {
 		// map, not under a default subkey
 		if s.Name() == ini.DefaultSection {
 			m = *out
+		} else if opts.NestedSections {
+			m, err = sectionMap(*out, strings.Split(s.Name(), "."))
+			if err != nil {
+				return err
+			}
 		} else {
 			m = make(map[string]interface{})
 			(*out)[s.Name()] = m
 		}
 
 		for _, k := range s.Keys() {
-			m[k.Name()] = k.Value()
+			if _, ok := m[k.Name()].(map[string]interface{}); ok {
+				return fmt.Errorf("key %q in section [%s] conflicts with a sub-section of the same name", k.Name(), s.Name())
+			}
+			values := k.ValueWithShadows()
+			if opts.AllowShadows && len(values) > 1 {
+				list := make([]interface{}, len(values))
+				for i, v := range values {
+					list[i] = value(v, opts)
+				}
+				m[k.Name()] = list
+			} else {
+				m[k.Name()] = value(k.Value(), opts)
+			}
 		}
 	}
 
 	return nil
 }
+
+// sectionMap returns the map for the section at path, creating the parent
+// maps as needed.
+func sectionMap(root map[string]interface{}, path []string) (map[string]interface{}, error) {
+	m := root
+	for i, name := range path {
+		switch existing := m[name].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			m[name] = child
+			m = child
+		case map[string]interface{}:
+			m = existing
+		default:
+			return nil, fmt.Errorf("section [%s] conflicts with key %q", strings.Join(path, "."), strings.Join(path[:i+1], "."))
+		}
+	}
+	return m, nil
+}
+
+// value converts v to a bool, int or float when typed values are enabled.
+func value(v string, opts Options) interface{} {
+	if !opts.TypedValues {
+		return v
+	}
+	switch strings.ToLower(v) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return int(i)
+	}
+	// ParseFloat also accepts "inf", "nan" and hex notation, which should
+	// stay strings.
+	if strings.ContainsAny(v, "0123456789") && !strings.ContainsAny(v, "xXpP") {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return v
+}
@@ -25,3 +25,87 @@ example_key = example_value`)
 		t.Errorf("Unexpected output, expected %v, got %v", expectedOutput, data)
 	}
 }
+
+func TestUnmarshalWithOptions(t *testing.T) {
+	input := []byte(`; Comment!
+rootKey = foo
+enabled = true
+[server]
+port = 8080
+ratio = 0.5
+version = 1.2.3
+[server.tls]
+# Another comment
+cert = a.pem
+[backend]
+host = one
+host = two`)
+	tc := []struct {
+		name     string
+		opts     Options
+		expected map[string]interface{}
+	}{
+		{
+			name: "defaults keep flat string sections",
+			opts: Options{},
+			expected: map[string]interface{}{
+				"rootKey": "foo",
+				"enabled": "true",
+				"server": map[string]interface{}{
+					"port":    "8080",
+					"ratio":   "0.5",
+					"version": "1.2.3",
+				},
+				"server.tls": map[string]interface{}{
+					"cert": "a.pem",
+				},
+				"backend": map[string]interface{}{
+					"host": "two",
+				},
+			},
+		},
+		{
+			name: "all options",
+			opts: Options{NestedSections: true, TypedValues: true, AllowShadows: true},
+			expected: map[string]interface{}{
+				"rootKey": "foo",
+				"enabled": true,
+				"server": map[string]interface{}{
+					"port":    8080,
+					"ratio":   0.5,
+					"version": "1.2.3",
+					"tls": map[string]interface{}{
+						"cert": "a.pem",
+					},
+				},
+				"backend": map[string]interface{}{
+					"host": []interface{}{"one", "two"},
+				},
+			},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var data map[string]interface{}
+			err := UnmarshalWithOptions(input, &data, c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expected, data) {
+				t.Errorf("Unexpected output, expected %v, got %v", c.expected, data)
+			}
+		})
+	}
+}
+
+func TestUnmarshalWithOptions_conflict(t *testing.T) {
+	input := []byte(`[a]
+b = value
+[a.b]
+c = value`)
+	var data map[string]interface{}
+	err := UnmarshalWithOptions(input, &data, Options{NestedSections: true})
+	if err == nil {
+		t.Errorf("Expected an error for a key conflicting with a sub-section, got %v", data)
+	}
+}
@@ -0,0 +1,22 @@
+package sops
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Subsystems of the provider logs. Their level follows TF_LOG_PROVIDER, and
+// can be set separately with TF_LOG_PROVIDER_SOPS_<SUBSYSTEM>, for instance
+// TF_LOG_PROVIDER_SOPS_KEYSERVICE=trace.
+const (
+	logEncrypt    = "encrypt"
+	logDecrypt    = "decrypt"
+	logKeyservice = "keyservice"
+)
+
+// withLogSubsystem returns ctx with the subsystem logger set up, so that
+// tflog.Subsystem* calls don't fall back to the root logger
+func withLogSubsystem(ctx context.Context, subsystem string) context.Context {
+	return tflog.NewSubsystem(ctx, subsystem, tflog.WithLevelFromEnv("TF_LOG_PROVIDER_SOPS", subsystem))
+}
@@ -0,0 +1,97 @@
+package sops
+
+import "fmt"
+
+// normalizeTree converts the map[interface{}]interface{} values produced by
+// the YAML decoder into map[string]interface{}, so trees from every format
+// can be merged and encoded the same way.
+func normalizeTree(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			m[fmt.Sprint(k)] = normalizeTree(v)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			m[k] = normalizeTree(v)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(typed))
+		for i, v := range typed {
+			l[i] = normalizeTree(v)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// mergeTree deep-merges the normalized tree src over dst. Maps are merged key
+// by key, lists are replaced or, with appendLists, concatenated, and any
+// other value from src replaces the one in dst.
+//
+// origin has the same shape as dst with the source of every leaf in place of
+// its value; the returned origin tracks the merged tree the same way, with
+// the leaves coming from src attributed to source. Flattening it gives the
+// provenance of every flattened key.
+func mergeTree(dst, origin, src interface{}, source string, appendLists bool) (interface{}, interface{}) {
+	switch typedSrc := src.(type) {
+	case map[string]interface{}:
+		typedDst, ok := dst.(map[string]interface{})
+		if !ok {
+			break
+		}
+		typedOrigin := origin.(map[string]interface{})
+		merged := make(map[string]interface{}, len(typedDst))
+		mergedOrigin := make(map[string]interface{}, len(typedDst))
+		for k, v := range typedDst {
+			merged[k] = v
+			mergedOrigin[k] = typedOrigin[k]
+		}
+		for k, v := range typedSrc {
+			if existing, ok := merged[k]; ok {
+				merged[k], mergedOrigin[k] = mergeTree(existing, mergedOrigin[k], v, source, appendLists)
+			} else {
+				merged[k], mergedOrigin[k] = v, originTree(v, source)
+			}
+		}
+		return merged, mergedOrigin
+	case []interface{}:
+		typedDst, ok := dst.([]interface{})
+		if !ok || !appendLists {
+			break
+		}
+		typedOrigin := origin.([]interface{})
+		merged := append(append([]interface{}{}, typedDst...), typedSrc...)
+		mergedOrigin := append([]interface{}{}, typedOrigin...)
+		for _, v := range typedSrc {
+			mergedOrigin = append(mergedOrigin, originTree(v, source))
+		}
+		return merged, mergedOrigin
+	}
+	return src, originTree(src, source)
+}
+
+// originTree mirrors the shape of v with source in place of every leaf
+func originTree(v interface{}, source string) interface{} {
+	switch typed := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			m[k] = originTree(v, source)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(typed))
+		for i, v := range typed {
+			l[i] = originTree(v, source)
+		}
+		return l
+	default:
+		return source
+	}
+}
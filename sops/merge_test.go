@@ -0,0 +1,95 @@
+package sops
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeTree(t *testing.T) {
+	base := map[string]interface{}{
+		"hello": "world",
+		"db": map[interface{}]interface{}{
+			"user":     "foo",
+			"password": "bar",
+		},
+		"a_list": []interface{}{"val1", "val2"},
+	}
+	override := map[string]interface{}{
+		"db": map[string]interface{}{
+			"password": "baz",
+		},
+		"a_list": []interface{}{"val3"},
+	}
+	tc := []struct {
+		name               string
+		appendLists        bool
+		expected           map[string]interface{}
+		expectedProvenance map[string]string
+	}{
+		{
+			name: "lists are replaced",
+			expected: map[string]interface{}{
+				"hello": "world",
+				"db": map[string]interface{}{
+					"user":     "foo",
+					"password": "baz",
+				},
+				"a_list": []interface{}{"val3"},
+			},
+			expectedProvenance: map[string]string{
+				"hello":       "base",
+				"db.user":     "base",
+				"db.password": "override",
+				"a_list.0":    "override",
+			},
+		},
+		{
+			name:        "lists are appended",
+			appendLists: true,
+			expected: map[string]interface{}{
+				"hello": "world",
+				"db": map[string]interface{}{
+					"user":     "foo",
+					"password": "baz",
+				},
+				"a_list": []interface{}{"val1", "val2", "val3"},
+			},
+			expectedProvenance: map[string]string{
+				"hello":       "base",
+				"db.user":     "base",
+				"db.password": "override",
+				"a_list.0":    "base",
+				"a_list.1":    "base",
+				"a_list.2":    "override",
+			},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var merged, origin interface{} = map[string]interface{}{}, map[string]interface{}{}
+			merged, origin = mergeTree(merged, origin, normalizeTree(base), "base", c.appendLists)
+			merged, origin = mergeTree(merged, origin, normalizeTree(override), "override", c.appendLists)
+			if !reflect.DeepEqual(c.expected, merged) {
+				t.Errorf("Unexpected merge output, expected %v, got %v", c.expected, merged)
+			}
+			provenance := flatten(origin.(map[string]interface{}))
+			if !reflect.DeepEqual(c.expectedProvenance, provenance) {
+				t.Errorf("Unexpected provenance, expected %v, got %v", c.expectedProvenance, provenance)
+			}
+		})
+	}
+}
+
+func TestMergeTree_typeChange(t *testing.T) {
+	var merged, origin interface{} = map[string]interface{}{}, map[string]interface{}{}
+	merged, origin = mergeTree(merged, origin, map[string]interface{}{"a": map[string]interface{}{"b": "c"}}, "base", false)
+	merged, origin = mergeTree(merged, origin, map[string]interface{}{"a": "scalar"}, "override", false)
+	expected := map[string]interface{}{"a": "scalar"}
+	if !reflect.DeepEqual(expected, merged) {
+		t.Errorf("Unexpected merge output, expected %v, got %v", expected, merged)
+	}
+	expectedProvenance := map[string]string{"a": "override"}
+	if provenance := flatten(origin.(map[string]interface{})); !reflect.DeepEqual(expectedProvenance, provenance) {
+		t.Errorf("Unexpected provenance, expected %v, got %v", expectedProvenance, provenance)
+	}
+}
@@ -1,14 +1,62 @@
 package sops
 
+import "fmt"
+
 type EncryptConfig struct {
-	Kms KmsConf
-	Age string
+	Kms    KmsConf
+	Age    []string
+	GcpKms []string
+	// GcpCredentials is the service account key used to access the GCP KMS
+	// keys, instead of the credentials of the environment
+	GcpCredentials []byte
+	// Unknown is set when part of the provider configuration is only known
+	// at apply, so the keys above can't be relied on during plan
+	Unknown bool
 }
 type KmsConf struct {
 	ARN     string
 	Profile string
+	Role    string
+	Context map[string]string
 }
 
 func (c *KmsConf) IsConfigured() bool {
-	return len(c.ARN) > 0 && len(c.Profile) > 0
+	return len(c.ARN) > 0
+}
+
+// KeyConf holds the keys configured on a sops_file resource
+type KeyConf struct {
+	Kms    *KmsConf
+	Age    []string
+	GcpKms []string
+	Pgp    []string
+	Azkv   []string
+	Vault  []string
+}
+
+// isEmpty reports whether no key is configured at all
+func (c KeyConf) isEmpty() bool {
+	return c.Kms == nil && len(c.Age) == 0 && len(c.GcpKms) == 0 &&
+		len(c.Pgp) == 0 && len(c.Azkv) == 0 && len(c.Vault) == 0
+}
+
+func (c KeyConf) kmsConf() (KmsConf, error) {
+	if c.Kms == nil || c.Kms.ARN == "" {
+		return KmsConf{}, fmt.Errorf("arn is not set")
+	}
+	return *c.Kms, nil
+}
+
+func (c KeyConf) ageRecipients() ([]string, error) {
+	if len(c.Age) == 0 {
+		return nil, fmt.Errorf("age recipients are not set")
+	}
+	return c.Age, nil
+}
+
+func (c KeyConf) gcpKmsIDs() ([]string, error) {
+	if len(c.GcpKms) == 0 {
+		return nil, fmt.Errorf("gcpkms resource_ids are not set")
+	}
+	return c.GcpKms, nil
 }
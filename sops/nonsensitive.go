@@ -0,0 +1,63 @@
+package sops
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+)
+
+// cleartextSelector reads the metadata of encrypted content and returns a
+// function reporting whether sops left the value at a path of map keys
+// unencrypted, following the same rules as sops' Tree.Encrypt for
+// unencrypted_suffix, encrypted_suffix, unencrypted_regex and encrypted_regex.
+func cleartextSelector(content []byte, format string) (func(keys []string) bool, error) {
+	if format == "raw" {
+		// Binary files are encrypted as a whole
+		return func([]string) bool { return false }, nil
+	}
+	store := storeForFormat(formats.FormatFromString(format))
+	tree, err := store.LoadEncryptedFile(content)
+	if err != nil {
+		return nil, err
+	}
+	md := tree.Metadata
+
+	var unencryptedRegex, encryptedRegex *regexp.Regexp
+	if md.UnencryptedRegex != "" {
+		if unencryptedRegex, err = regexp.Compile(md.UnencryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid unencrypted_regex in sops metadata: %s", err)
+		}
+	}
+	if md.EncryptedRegex != "" {
+		if encryptedRegex, err = regexp.Compile(md.EncryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid encrypted_regex in sops metadata: %s", err)
+		}
+	}
+
+	anyKey := func(keys []string, match func(string) bool) bool {
+		for _, k := range keys {
+			if match(k) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(keys []string) bool {
+		encrypted := true
+		if md.UnencryptedSuffix != "" && anyKey(keys, func(k string) bool { return strings.HasSuffix(k, md.UnencryptedSuffix) }) {
+			encrypted = false
+		}
+		if md.EncryptedSuffix != "" {
+			encrypted = anyKey(keys, func(k string) bool { return strings.HasSuffix(k, md.EncryptedSuffix) })
+		}
+		if unencryptedRegex != nil && anyKey(keys, unencryptedRegex.MatchString) {
+			encrypted = false
+		}
+		if encryptedRegex != nil {
+			encrypted = anyKey(keys, encryptedRegex.MatchString)
+		}
+		return !encrypted
+	}, nil
+}
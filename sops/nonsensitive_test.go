@@ -0,0 +1,39 @@
+package sops
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestCleartextSelector(t *testing.T) {
+	tc := []struct {
+		file     string
+		format   string
+		keys     []string
+		expected bool
+	}{
+		{file: "test-fixtures/unencrypted-regex.yaml", format: "yaml", keys: []string{"region"}, expected: true},
+		{file: "test-fixtures/unencrypted-regex.yaml", format: "yaml", keys: []string{"hosts"}, expected: true},
+		{file: "test-fixtures/unencrypted-regex.yaml", format: "yaml", keys: []string{"db", "region"}, expected: true},
+		{file: "test-fixtures/unencrypted-regex.yaml", format: "yaml", keys: []string{"db", "password"}, expected: false},
+		{file: "test-fixtures/unencrypted-suffix.env", format: "dotenv", keys: []string{"hostname_unencrypted"}, expected: true},
+		{file: "test-fixtures/unencrypted-suffix.env", format: "dotenv", keys: []string{"password"}, expected: false},
+		{file: "test-fixtures/basic.yaml", format: "yaml", keys: []string{"hello"}, expected: false},
+		{file: "test-fixtures/raw.txt", format: "raw", keys: []string{"data"}, expected: false},
+	}
+	for _, c := range tc {
+		t.Run(c.file, func(t *testing.T) {
+			content, err := ioutil.ReadFile(c.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			isCleartext, err := cleartextSelector(content, c.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := isCleartext(c.keys); actual != c.expected {
+				t.Errorf("Expected %v in %s to be cleartext: %t, got %t", c.keys, c.file, c.expected, actual)
+			}
+		})
+	}
+}
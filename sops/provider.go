@@ -1,64 +1,39 @@
 package sops
 
 import (
-	"context"
-	"fmt"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
+// Provider serves the data sources that are still implemented with
+// helper/schema. It has no provider schema: helper/schema can't express the
+// kms, age and gcpkms objects, so the configuration is declared, validated
+// and used by the framework provider alone (see ProviderServerFactory).
 func Provider() *schema.Provider {
 	return &schema.Provider{
-		Schema: map[string]*schema.Schema{
-			"kms": {
-				Type:        schema.TypeMap,
-				Optional:    true,
-				Description: providerDescriptions["kms"],
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
-			},
-			"age": {
-				Type:        schema.TypeMap,
-				Optional:    true,
-				Description: providerDescriptions["age"],
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
-			},
-		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"sops_file":       dataSourceFile(),
-			"sops_file_entry": dataSourceFileKey(),
-			"sops_external":   dataSourceExternal(),
-		},
-		ResourcesMap: map[string]*schema.Resource{
-			"sops_file": resourceSourceFile(),
+			"sops_file":        dataSourceFile(),
+			"sops_file_entry":  dataSourceFileKey(),
+			"sops_external":    dataSourceExternal(),
+			"sops_remote_file": dataSourceRemoteFile(),
+			"sops_git_file":    dataSourceGitFile(),
+			"sops_merged":      dataSourceMerged(),
 		},
-		ConfigureContextFunc: ConfigureProvider,
 	}
 }
 
 var providerDescriptions = map[string]string{
-	"kms": "Configuration for encrypt files with AWS KMS.",
-	"age": "Configuration for encrypt files with Age.",
-}
-
-func ConfigureProvider(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
-	var diags diag.Diagnostics
-	encConf := &EncryptConfig{}
-	kms, err := GetKmsConf(d)
-	if err != nil {
-		fmt.Println("failed to init kms")
-	} else {
-		encConf.Kms = kms
-	}
-	age, err := GetAgeConf(d)
-	if err != nil {
-		fmt.Println("failed to init age")
-	} else {
-		encConf.Age = age
-	}
-
-	return encConf, diags
+	"kms":                     "Configuration for encrypt files with AWS KMS.",
+	"kms.arn":                 "ARN of the KMS key, or a comma separated list of ARNs.",
+	"kms.profile":             "AWS profile used to access the key.",
+	"kms.role":                "ARN of an IAM role to assume to access the key.",
+	"kms.context":             "KMS encryption context.",
+	"age":                     "Configuration for encrypt files with Age.",
+	"age.key":                 "Comma separated list of age recipients.",
+	"age.recipients":          "Age or SSH public keys to encrypt for.",
+	"age.recipients_file":     "Path of a file listing age or SSH public keys, one per line.",
+	"age.identity_file":       "Path of an age key file, whose public keys are encrypted for.",
+	"gcpkms":                  "Configuration for encrypt files with GCP KMS.",
+	"gcpkms.resource_ids":     "Resource IDs of the GCP KMS keys to encrypt with.",
+	"gcpkms.credentials":      "Content of the service account key file used to access the keys.",
+	"gcpkms.credentials_file": "Path of the service account key file used to access the keys.",
 }
@@ -1,13 +1,35 @@
 package sops
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	providerschema "github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
 var testAccProviders map[string]*schema.Provider
 var testAccProvider *schema.Provider
 
+// testAccProtoV6ProviderFactories serves the muxed provider, for tests of
+// the features only the framework provider implements
+var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
+	"sops": func() (tfprotov6.ProviderServer, error) {
+		factory, err := ProviderServerFactory(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		return factory(), nil
+	},
+}
+
 func init() {
 	testAccProvider = Provider()
 	testAccProviders = map[string]*schema.Provider{
@@ -24,3 +46,164 @@ func TestProvider(t *testing.T) {
 func TestProvider_impl(t *testing.T) {
 	var _ *schema.Provider = Provider()
 }
+
+func TestProviderServerFactory(t *testing.T) {
+	factory, err := ProviderServerFactory(context.Background())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	// The provider schemas of the muxed servers are only compared when the
+	// combined schema is requested
+	resp, err := factory().GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, d := range resp.Diagnostics {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Errorf("%s: %s", d.Summary, d.Detail)
+		}
+	}
+	// kms, age and gcpkms are attributes, so they keep the `kms = { ... }`
+	// syntax of the map attributes they replace
+	for _, name := range []string{"kms", "age", "gcpkms"} {
+		found := false
+		for _, attr := range resp.Provider.Block.Attributes {
+			found = found || (attr.Name == name && attr.NestedType != nil)
+		}
+		if !found {
+			t.Errorf("expected %s to be a nested attribute of the provider", name)
+		}
+	}
+}
+
+func testFrameworkProviderType(t *testing.T) tftypes.Object {
+	t.Helper()
+	var schemaResp provider.SchemaResponse
+	NewFrameworkProvider().Schema(context.Background(), provider.SchemaRequest{}, &schemaResp)
+	return schemaResp.Schema.Type().TerraformType(context.Background()).(tftypes.Object)
+}
+
+func testFrameworkProviderConfigure(t *testing.T, blocks map[string]tftypes.Value) *provider.ConfigureResponse {
+	t.Helper()
+	ctx := context.Background()
+	p := NewFrameworkProvider()
+	var schemaResp provider.SchemaResponse
+	p.Schema(ctx, provider.SchemaRequest{}, &schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := make(map[string]tftypes.Value, len(objType.AttributeTypes))
+	for name, typ := range objType.AttributeTypes {
+		if v, ok := blocks[name]; ok {
+			values[name] = v
+		} else {
+			values[name] = tftypes.NewValue(typ, nil)
+		}
+	}
+	config := tftypes.NewValue(objType, values)
+
+	resp := &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: config}}, resp)
+	return resp
+}
+
+func TestFrameworkProviderConfigure_incomplete(t *testing.T) {
+	kmsObject := testFrameworkProviderType(t).AttributeTypes["kms"].(tftypes.Object)
+
+	kms := map[string]tftypes.Value{}
+	for name, typ := range kmsObject.AttributeTypes {
+		kms[name] = tftypes.NewValue(typ, nil)
+	}
+	kms["profile"] = tftypes.NewValue(tftypes.String, "default")
+	resp := testFrameworkProviderConfigure(t, map[string]tftypes.Value{
+		"kms": tftypes.NewValue(kmsObject, kms),
+	})
+	if resp.Diagnostics.HasError() || resp.Diagnostics.WarningsCount() != 1 {
+		t.Fatalf("expected a single warning, got %v", resp.Diagnostics)
+	}
+	if summary := resp.Diagnostics.Warnings()[0].Summary(); summary != "Incomplete kms configuration" {
+		t.Errorf("unexpected warning %q", summary)
+	}
+	if conf := resp.ResourceData.(*EncryptConfig); conf.Kms.IsConfigured() || conf.Unknown {
+		t.Errorf("unexpected configuration %+v", conf)
+	}
+}
+
+func TestFrameworkProviderConfigure_gcpkms(t *testing.T) {
+	t.Setenv("GOOGLE_CREDENTIALS", "")
+	gcpKmsObject := testFrameworkProviderType(t).AttributeTypes["gcpkms"].(tftypes.Object)
+	resourceIDsType := gcpKmsObject.AttributeTypes["resource_ids"]
+	resourceID := "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+	if err := ioutil.WriteFile(keyFile, []byte(`{"type": "service_account"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := testFrameworkProviderConfigure(t, map[string]tftypes.Value{
+		"gcpkms": tftypes.NewValue(gcpKmsObject, map[string]tftypes.Value{
+			"resource_ids":     tftypes.NewValue(resourceIDsType, []tftypes.Value{tftypes.NewValue(tftypes.String, resourceID)}),
+			"credentials":      tftypes.NewValue(tftypes.String, nil),
+			"credentials_file": tftypes.NewValue(tftypes.String, keyFile),
+		}),
+	})
+	if len(resp.Diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+	conf := resp.ResourceData.(*EncryptConfig)
+	if len(conf.GcpKms) != 1 || conf.GcpKms[0] != resourceID {
+		t.Errorf("expected the resource ID to be configured, got %+v", conf)
+	}
+	if string(conf.GcpCredentials) != `{"type": "service_account"}` {
+		t.Errorf("expected the content of the key file as credentials, got %q", conf.GcpCredentials)
+	}
+	if credentials := os.Getenv("GOOGLE_CREDENTIALS"); credentials != "" {
+		t.Errorf("expected GOOGLE_CREDENTIALS to be left unset, got %q", credentials)
+	}
+
+	resp = testFrameworkProviderConfigure(t, map[string]tftypes.Value{
+		"gcpkms": tftypes.NewValue(gcpKmsObject, map[string]tftypes.Value{
+			"resource_ids":     tftypes.NewValue(resourceIDsType, nil),
+			"credentials":      tftypes.NewValue(tftypes.String, nil),
+			"credentials_file": tftypes.NewValue(tftypes.String, filepath.Join(t.TempDir(), "missing.json")),
+		}),
+	})
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected a missing credentials_file to be an error")
+	}
+}
+
+func TestProvider_descriptions(t *testing.T) {
+	var schemaResp provider.SchemaResponse
+	NewFrameworkProvider().Schema(context.Background(), provider.SchemaRequest{}, &schemaResp)
+	var check func(prefix string, attrs map[string]providerschema.Attribute)
+	check = func(prefix string, attrs map[string]providerschema.Attribute) {
+		for name, attr := range attrs {
+			if attr.GetDescription() == "" {
+				t.Errorf("%s%s has no description", prefix, name)
+			}
+			if nested, ok := attr.(providerschema.SingleNestedAttribute); ok {
+				check(prefix+name+".", nested.Attributes)
+			}
+		}
+	}
+	check("", schemaResp.Schema.Attributes)
+}
+
+func TestFrameworkProviderConfigure_ageKey(t *testing.T) {
+	ageObject := testFrameworkProviderType(t).AttributeTypes["age"].(tftypes.Object)
+	recipient := "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
+
+	age := map[string]tftypes.Value{}
+	for name, typ := range ageObject.AttributeTypes {
+		age[name] = tftypes.NewValue(typ, nil)
+	}
+	age["key"] = tftypes.NewValue(tftypes.String, recipient)
+	resp := testFrameworkProviderConfigure(t, map[string]tftypes.Value{
+		"age": tftypes.NewValue(ageObject, age),
+	})
+	if len(resp.Diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+	if conf := resp.ResourceData.(*EncryptConfig); len(conf.Age) != 1 || conf.Age[0] != recipient {
+		t.Errorf("expected the deprecated key to be used, got %+v", conf)
+	}
+}
@@ -1,50 +1,50 @@
 package sops
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+
+	"github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/decrypt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"go.mozilla.org/sops/v3"
-	"go.mozilla.org/sops/v3/decrypt"
 	"gopkg.in/yaml.v2"
 
-	"github.com/lokkersp/terraform-provider-sops/sops/internal/dotenv"
-	"github.com/lokkersp/terraform-provider-sops/sops/internal/ini"
+	"github.com/mattclegg/terraform-provider-sops/sops/sops/internal/dotenv"
+	"github.com/mattclegg/terraform-provider-sops/sops/sops/internal/ini"
 )
 
 // readData consolidates the logic of extracting the from the various input methods and setting it on the ResourceData
-func readData(content []byte, format string, d *schema.ResourceData) error {
-	cleartext, err := decrypt.Data(content, format)
-	if userErr, ok := err.(sops.UserError); ok {
-		err = fmt.Errorf(userErr.UserError())
-	}
+func readData(ctx context.Context, content []byte, format string, d *schema.ResourceData) error {
+	decoded, err := decodeData(ctx, content, format, getIniOptions(d), getFlattenOptions(d))
 	if err != nil {
 		return err
 	}
 
 	// Set output attribute for raw content
-	err = d.Set("raw", string(cleartext))
+	err = d.Set("raw", decoded.raw)
 	if err != nil {
 		return err
 	}
 
 	// Set output attribute for content as a map (only for json and yaml)
-	var data map[string]interface{}
-	switch format {
-	case "json":
-		err = json.Unmarshal(cleartext, &data)
-	case "yaml":
-		err = yaml.Unmarshal(cleartext, &data)
-	case "dotenv":
-		err = dotenv.Unmarshal(cleartext, &data)
-	case "ini":
-		err = ini.Unmarshal(cleartext, &data)
+	err = d.Set("data", decoded.data)
+	if err != nil {
+		return err
 	}
+
+	// Set output attribute for the values sops left unencrypted
+	err = d.Set("nonsensitive_data", decoded.nonsensitive)
 	if err != nil {
 		return err
 	}
 
-	err = d.Set("data", flatten(data))
+	// Set output attribute for each document of a multi-document file
+	err = d.Set("documents", decoded.documents)
 	if err != nil {
 		return err
 	}
@@ -53,9 +53,60 @@ func readData(content []byte, format string, d *schema.ResourceData) error {
 	return nil
 }
 
+// decodedData holds the outputs derived from sops-encrypted content
+type decodedData struct {
+	raw          string
+	tree         map[string]interface{}
+	data         map[string]string
+	nonsensitive map[string]string
+	// documents holds the flattened data of each document, as YAML files
+	// may contain several; other formats have a single document
+	documents []map[string]string
+}
+
+// decodeData decrypts content and derives the raw, flattened, nonsensitive
+// and per-document outputs from it. data and nonsensitive are derived from
+// the first document.
+func decodeData(ctx context.Context, content []byte, format string, iniOpts ini.Options, flattenOpts flattenOptions) (*decodedData, error) {
+	cleartext, err := decryptData(ctx, content, format)
+	if err != nil {
+		return nil, err
+	}
+	decoded := &decodedData{raw: string(cleartext)}
+
+	documents, err := unmarshalDocuments(cleartext, format, iniOpts)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) > 0 {
+		decoded.tree = documents[0]
+	}
+	decoded.documents = make([]map[string]string, len(documents))
+	for i, document := range documents {
+		decoded.documents[i], err = flattenWithOptions(document, flattenOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	decoded.data, err = flattenWithOptions(decoded.tree, flattenOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	isCleartext, err := cleartextSelector(content, format)
+	if err != nil {
+		return nil, err
+	}
+	decoded.nonsensitive, err = flattenSelected(decoded.tree, flattenOpts, isCleartext)
+	if err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
 // readData consolidates the logic of extracting the from the various input methods and setting it on the ResourceData
-func readDataKey(content []byte, format string, key string, d *schema.ResourceData) error {
-	cleartext, err := decrypt.Data(content, format)
+func readDataKey(ctx context.Context, content []byte, format string, key string, d *schema.ResourceData) error {
+	cleartext, err := decryptData(ctx, content, format)
 	if err != nil {
 		return fmt.Errorf("fail to decrypt,format is %s:%s", format, err)
 	}
@@ -67,33 +118,51 @@ func readDataKey(content []byte, format string, key string, d *schema.ResourceDa
 	}
 
 	// Set output attribute for content as a map (only for json and yaml)
-	var data map[string]interface{}
-	switch format {
-	case "json":
-		err = json.Unmarshal(cleartext, &data)
-	case "yaml":
-		err = yaml.Unmarshal(cleartext, &data)
-	case "dotenv":
-		err = dotenv.Unmarshal(cleartext, &data)
-	case "ini":
-		err = ini.Unmarshal(cleartext, &data)
-	}
+	data, err := unmarshalData(cleartext, format, getIniOptions(d))
 	if err != nil {
 		return fmt.Errorf("evaluated format is %s:%s", err, format)
 	}
 
-	flData := flatten(data)
+	flattenOpts := getFlattenOptions(d)
+	flData, err := flattenWithOptions(data, flattenOpts)
+	if err != nil {
+		return err
+	}
 
-	err = d.Set("data", flData[key])
+	err = d.Set("data", flData[newFlattener(flattenOpts).child("", key)])
 	if err != nil {
 		return err
 	}
-	err, value := flattenFromKey(data, key)
+	// A missing key reads as an empty map, as it always did
+	value := make(map[string]string)
+	if data[key] != nil {
+		if value, err = flattenFromKey(data, key, flattenOpts); err != nil {
+			return err
+		}
+	}
 	out, err := yaml.Marshal(map[string]interface{}{key: data[key]})
 	if err != nil {
 		return err
 	}
 	err = d.Set("map", value)
+	if err != nil {
+		return err
+	}
+	isCleartext, err := cleartextSelector(content, format)
+	if err != nil {
+		return err
+	}
+	nonsensitive := make(map[string]string)
+	if data[key] != nil {
+		nonsensitive, err = flattenSelected(map[string]interface{}{key: data[key]}, flattenOpts, isCleartext)
+		if err != nil {
+			return err
+		}
+	}
+	err = d.Set("nonsensitive_data", nonsensitive)
+	if err != nil {
+		return err
+	}
 	err = d.Set("yaml", string(out))
 	if err != nil {
 		return err
@@ -101,3 +170,58 @@ func readDataKey(content []byte, format string, key string, d *schema.ResourceDa
 	d.SetId("-")
 	return nil
 }
+
+// unmarshalData decodes cleartext of the given format into a tree
+func unmarshalData(cleartext []byte, format string, iniOpts ini.Options) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	var err error
+	switch format {
+	case "json":
+		err = json.Unmarshal(cleartext, &data)
+	case "yaml":
+		err = yaml.Unmarshal(cleartext, &data)
+	case "dotenv":
+		err = dotenv.Unmarshal(cleartext, &data)
+	case "ini":
+		err = ini.UnmarshalWithOptions(cleartext, &data, iniOpts)
+	}
+	return data, err
+}
+
+// unmarshalDocuments decodes each document of cleartext into a tree. Only
+// YAML has several documents, separated by ---; empty documents are skipped.
+func unmarshalDocuments(cleartext []byte, format string, iniOpts ini.Options) ([]map[string]interface{}, error) {
+	if format != "yaml" {
+		data, err := unmarshalData(cleartext, format, iniOpts)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]interface{}{data}, nil
+	}
+	var documents []map[string]interface{}
+	decoder := yaml.NewDecoder(bytes.NewReader(cleartext))
+	for {
+		var data map[string]interface{}
+		err := decoder.Decode(&data)
+		if err == io.EOF {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %s", len(documents)+1, err)
+		}
+		if data != nil {
+			documents = append(documents, data)
+		}
+	}
+}
+
+// decryptData decrypts content, surfacing the user-facing message of sops errors
+func decryptData(ctx context.Context, content []byte, format string) ([]byte, error) {
+	ctx = withLogSubsystem(ctx, logDecrypt)
+	tflog.SubsystemDebug(ctx, logDecrypt, "Decrypting content", map[string]interface{}{"format": format})
+	cleartext, err := decrypt.Data(content, format)
+	if userErr, ok := err.(sops.UserError); ok {
+		err = errors.New(userErr.UserError())
+	}
+	return cleartext, err
+}
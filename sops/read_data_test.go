@@ -0,0 +1,69 @@
+package sops
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/mattclegg/terraform-provider-sops/sops/sops/internal/ini"
+)
+
+func TestUnmarshalDocuments(t *testing.T) {
+	documents, err := unmarshalDocuments([]byte("a: 1\n---\n---\nb: two\n"), "yaml", ini.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []map[string]interface{}{{"a": 1}, {"b": "two"}}
+	if !reflect.DeepEqual(documents, expected) {
+		t.Errorf("expected empty documents to be skipped, got %v", documents)
+	}
+
+	documents, err = unmarshalDocuments([]byte(`{"a": "b"}`), "json", ini.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(documents) != 1 || documents[0]["a"] != "b" {
+		t.Errorf("expected a single document, got %v", documents)
+	}
+
+	if _, err := unmarshalDocuments([]byte("a: 1\n---\n- b\n"), "yaml", ini.Options{}); err == nil {
+		t.Error("expected a document that isn't a map to fail")
+	}
+}
+
+func TestReadDataKey_missingKey(t *testing.T) {
+	content, err := ioutil.ReadFile("test-fixtures/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := schema.TestResourceDataRaw(t, dataSourceFileKey().Schema, map[string]interface{}{
+		"source_file": "test-fixtures/basic.yaml",
+		"data_key":    "missing",
+	})
+	if err := readDataKey(context.Background(), content, "yaml", "missing", d); err != nil {
+		t.Fatalf("expected a missing key to read as an empty map, got %s", err)
+	}
+	if value := d.Get("map").(map[string]interface{}); len(value) != 0 {
+		t.Errorf("expected an empty map, got %v", value)
+	}
+}
+
+func TestReadDataKey_nonsensitive(t *testing.T) {
+	content, err := ioutil.ReadFile("test-fixtures/unencrypted-regex.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := schema.TestResourceDataRaw(t, dataSourceFileKey().Schema, map[string]interface{}{
+		"source_file": "test-fixtures/unencrypted-regex.yaml",
+		"data_key":    "db",
+	})
+	if err := readDataKey(context.Background(), content, "yaml", "db", d); err != nil {
+		t.Fatal(err)
+	}
+	nonsensitive := d.Get("nonsensitive_data").(map[string]interface{})
+	if len(nonsensitive) != 1 || nonsensitive["db.region"] != "inner" {
+		t.Errorf("expected only db.region to be nonsensitive, got %v", nonsensitive)
+	}
+}
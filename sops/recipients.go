@@ -0,0 +1,47 @@
+package sops
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getsops/sops/v3/age"
+	"github.com/getsops/sops/v3/azkv"
+	"github.com/getsops/sops/v3/gcpkms"
+	"github.com/getsops/sops/v3/hcvault"
+	"github.com/getsops/sops/v3/keys"
+	"github.com/getsops/sops/v3/kms"
+	"github.com/getsops/sops/v3/pgp"
+)
+
+// recipientTypes lists the key types accepted in recipients, named like the
+// sops metadata
+var recipientTypes = []string{"age", "pgp", "kms", "gcp_kms", "azure_kv", "hc_vault"}
+
+// recipientMasterKey builds the master key of a recipient of the given type
+func recipientMasterKey(recipientType, recipient string) (keys.MasterKey, error) {
+	if recipient == "" {
+		return nil, fmt.Errorf("recipient is empty")
+	}
+	switch recipientType {
+	case "age":
+		ageKeys, err := age.MasterKeysFromRecipients(recipient)
+		if err != nil {
+			return nil, err
+		}
+		if len(ageKeys) != 1 {
+			return nil, fmt.Errorf("expected a single age recipient")
+		}
+		return ageKeys[0], nil
+	case "pgp":
+		return pgp.NewMasterKeyFromFingerprint(recipient), nil
+	case "kms":
+		return kms.NewMasterKeyFromArn(recipient, nil, ""), nil
+	case "gcp_kms":
+		return gcpkms.NewMasterKeyFromResourceID(recipient), nil
+	case "azure_kv":
+		return azkv.NewMasterKeyFromURL(recipient)
+	case "hc_vault":
+		return hcvault.NewMasterKeyFromURI(recipient)
+	}
+	return nil, fmt.Errorf("unknown recipient type, expected one of %s", strings.Join(recipientTypes, ", "))
+}
@@ -0,0 +1,32 @@
+package sops
+
+import (
+	"testing"
+)
+
+func TestRecipientMasterKey(t *testing.T) {
+	valid := map[string]string{
+		"age": "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p",
+		"pgp": "3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A",
+		"kms": "arn:aws:kms:us-east-1:123456789012:key/d7b1a4b5-1d3e-4ae4-9c32-5b9e1a5f5c3e",
+	}
+	for recipientType, recipient := range valid {
+		if _, err := recipientMasterKey(recipientType, recipient); err != nil {
+			t.Errorf("unexpected error for %s recipient %q: %s", recipientType, recipient, err)
+		}
+	}
+
+	invalid := []struct {
+		recipientType string
+		recipient     string
+	}{
+		{"age", "not-an-age-key"},
+		{"pgp", ""},
+		{"ssh", "ssh-ed25519 AAAA"},
+	}
+	for _, r := range invalid {
+		if _, err := recipientMasterKey(r.recipientType, r.recipient); err == nil {
+			t.Errorf("expected an error for %s recipient %q", r.recipientType, r.recipient)
+		}
+	}
+}
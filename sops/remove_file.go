@@ -0,0 +1,58 @@
+package sops
+
+import (
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// missingDirectories returns dir and its parents that don't exist yet, from
+// the deepest one, so they can be removed in order once they are empty
+func missingDirectories(dir string) []string {
+	var missing []string
+	for {
+		if _, err := os.Stat(dir); err == nil || !os.IsNotExist(err) {
+			return missing
+		}
+		missing = append(missing, dir)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return missing
+		}
+		dir = parent
+	}
+}
+
+// removeEmptyDirectories removes dirs, from the first one, until one of them
+// isn't empty or can't be removed
+func removeEmptyDirectories(dirs []string) {
+	for _, dir := range dirs {
+		if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+			return
+		}
+	}
+}
+
+// shredFile overwrites the content of filename with random bytes before it
+// is removed. Symbolic links are left to be removed as they are, since
+// their target may not belong to the resource.
+func shredFile(filename string) error {
+	info, err := os.Lstat(filename)
+	if err != nil || !info.Mode().IsRegular() {
+		return err
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(f, rand.Reader, info.Size()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,50 @@
+package sops
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMissingDirectories(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "b")
+	missing := missingDirectories(dir)
+	if len(missing) != 2 || missing[0] != dir || missing[1] != filepath.Join(root, "a") {
+		t.Fatalf("expected a/b and a to be missing, got %v", missing)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "a", "other"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	removeEmptyDirectories(missing)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected the empty directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a")); err != nil {
+		t.Errorf("expected the directory holding other files to be kept, got %v", err)
+	}
+}
+
+func TestShredFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.enc.yaml")
+	content := bytes.Repeat([]byte("secret"), 1000)
+	if err := ioutil.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := shredFile(filename); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	shredded, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shredded) != len(content) || bytes.Contains(shredded, []byte("secret")) {
+		t.Error("expected the content to be overwritten")
+	}
+}
@@ -0,0 +1,215 @@
+package sops
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.Resource = &resourceDecryptedFile{}
+
+// resourceDecryptedFile writes the cleartext of a sops-encrypted file to
+// disk, for tools that can only read their secrets from files
+type resourceDecryptedFile struct{}
+
+type resourceDecryptedFileModel struct {
+	ID                  types.String `tfsdk:"id"`
+	SourceFile          types.String `tfsdk:"source_file"`
+	InputType           types.String `tfsdk:"input_type"`
+	OutputType          types.String `tfsdk:"output_type"`
+	Filename            types.String `tfsdk:"filename"`
+	FilePermission      types.String `tfsdk:"file_permission"`
+	DirectoryPermission types.String `tfsdk:"directory_permission"`
+}
+
+func NewResourceDecryptedFile() resource.Resource {
+	return &resourceDecryptedFile{}
+}
+
+func (r *resourceDecryptedFile) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_decrypted_file"
+}
+
+func (r *resourceDecryptedFile) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Decrypts a sops-encrypted file to a cleartext file on disk.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "SHA-256 checksum of the decrypted file.",
+			},
+			"source_file": schema.StringAttribute{
+				Required:      true,
+				Description:   "Path to the encrypted file.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"input_type": schema.StringAttribute{
+				Optional:      true,
+				Description:   "Format of the encrypted file: json, yaml, dotenv, ini, raw or auto. Defaults to the format of the source_file extension.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"output_type": schema.StringAttribute{
+				Optional:      true,
+				Description:   "Format to write the cleartext in: json, yaml, dotenv, ini or raw. Defaults to the format of the encrypted file.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Validators:    []validator.String{outputTypeValidator},
+			},
+			"filename": schema.StringAttribute{
+				Required:      true,
+				Description:   "Path to write the cleartext to.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"file_permission": schema.StringAttribute{
+				Description:   "Permissions to set for the output file",
+				Optional:      true,
+				Computed:      true,
+				Default:       stringdefault.StaticString("0600"),
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Validators:    []validator.String{fileModeValidator},
+			},
+			"directory_permission": schema.StringAttribute{
+				Description:   "Permissions to set for directories created",
+				Optional:      true,
+				Computed:      true,
+				Default:       stringdefault.StaticString("0700"),
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Validators:    []validator.String{fileModeValidator},
+			},
+		},
+	}
+}
+
+func (r *resourceDecryptedFile) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var model resourceDecryptedFileModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	cleartext, err := model.cleartext(ctx)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source_file"), "Failed to decrypt sops file", err.Error())
+		return
+	}
+
+	destination := model.Filename.ValueString()
+	destinationDir := filepath.Dir(destination)
+	if _, err := os.Stat(destinationDir); err != nil {
+		dirMode, _ := strconv.ParseInt(model.DirectoryPermission.ValueString(), 8, 64)
+		if err := os.MkdirAll(destinationDir, os.FileMode(dirMode)); err != nil {
+			resp.Diagnostics.AddError("Failed to create directory", err.Error())
+			return
+		}
+	}
+
+	fileMode, _ := strconv.ParseInt(model.FilePermission.ValueString(), 8, 64)
+	if err := writeFileAtomic(destination, cleartext, os.FileMode(fileMode)); err != nil {
+		resp.Diagnostics.AddError("Failed to write file", err.Error())
+		return
+	}
+
+	model.ID = types.StringValue(sha256Hex(cleartext))
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r *resourceDecryptedFile) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var model resourceDecryptedFileModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// If the output file is missing or was modified externally, mark the
+	// resource for creation
+	outputContent, err := ioutil.ReadFile(model.Filename.ValueString())
+	if os.IsNotExist(err) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read file", err.Error())
+		return
+	}
+	if sha256Hex(outputContent) != model.ID.ValueString() {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// The output must also be recreated when the encrypted file changed
+	cleartext, err := model.cleartext(ctx)
+	if err != nil {
+		resp.Diagnostics.AddAttributeWarning(path.Root("source_file"), "Failed to decrypt sops file",
+			fmt.Sprintf("Changes to the encrypted file can't be detected: %s", err))
+		return
+	}
+	if sha256Hex(cleartext) != model.ID.ValueString() {
+		resp.State.RemoveResource(ctx)
+	}
+}
+
+func (r *resourceDecryptedFile) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// Every argument requires replacement, so there is nothing to update in
+	// place
+	resp.State.Raw = req.Plan.Raw
+}
+
+func (r *resourceDecryptedFile) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var filename types.String
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("filename"), &filename)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := os.Remove(filename.ValueString()); err != nil && !os.IsNotExist(err) {
+		resp.Diagnostics.AddError("Failed to remove file", err.Error())
+	}
+}
+
+// cleartext decrypts the source file and converts it to the output type
+func (m resourceDecryptedFileModel) cleartext(ctx context.Context) ([]byte, error) {
+	content, format, err := readSourceFile(m.SourceFile.ValueString(), m.InputType.ValueString())
+	if err != nil {
+		return nil, err
+	}
+	cleartext, err := decryptData(ctx, content, format)
+	if err != nil {
+		return nil, err
+	}
+	outputType := m.OutputType.ValueString()
+	if outputType == "" || outputType == format {
+		return cleartext, nil
+	}
+	return convertCleartext(cleartext, format, outputType)
+}
+
+// convertCleartext re-emits cleartext in the from format as the to format
+func convertCleartext(cleartext []byte, from, to string) ([]byte, error) {
+	branches, err := storeForFormat(formats.FormatFromString(from)).LoadPlainFile(cleartext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the %s cleartext: %s", from, err)
+	}
+	converted, err := storeForFormat(formats.FormatFromString(to)).EmitPlainFile(branches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the %s cleartext to %s: %s", from, to, err)
+	}
+	return converted, nil
+}
+
+func sha256Hex(content []byte) string {
+	checksum := sha256.Sum256(content)
+	return hex.EncodeToString(checksum[:])
+}
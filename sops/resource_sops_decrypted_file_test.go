@@ -0,0 +1,121 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	sdkresource "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const configTestResourceSopsDecryptedFile_json = `
+resource "sops_decrypted_file" "test_json" {
+  source_file = "%s/test-fixtures/basic.yaml"
+  output_type = "json"
+  filename    = "%s"
+}`
+
+func TestResourceSopsDecryptedFile_json(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "secrets", "basic.json")
+	sdkresource.UnitTest(t, sdkresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []sdkresource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsDecryptedFile_json, wd, filename),
+				Check: sdkresource.ComposeTestCheckFunc(
+					sdkresource.TestCheckResourceAttr("sops_decrypted_file.test_json", "file_permission", "0600"),
+					testCheckDecryptedFile(filename, `"hello": "world"`, 0600),
+				),
+			},
+		},
+	})
+}
+
+func testCheckDecryptedFile(filename, expected string, mode os.FileMode) sdkresource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm() != mode {
+			return fmt.Errorf("expected %s to have mode %o, got %o", filename, mode, info.Mode().Perm())
+		}
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(content), expected) {
+			return fmt.Errorf("expected %s to contain %s, got %s", filename, expected, content)
+		}
+		return nil
+	}
+}
+
+func TestResourceSopsDecryptedFile_drift(t *testing.T) {
+	ctx := context.Background()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewResourceDecryptedFile()
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	filename := filepath.Join(t.TempDir(), "basic.yaml")
+	values := make(map[string]tftypes.Value, len(objType.AttributeTypes))
+	for name, typ := range objType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["source_file"] = tftypes.NewValue(tftypes.String, filepath.Join(wd, "test-fixtures", "basic.yaml"))
+	values["filename"] = tftypes.NewValue(tftypes.String, filename)
+	values["file_permission"] = tftypes.NewValue(tftypes.String, "0600")
+	values["directory_permission"] = tftypes.NewValue(tftypes.String, "0700")
+	plan := tfsdk.Plan{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, values)}
+
+	createResp := &resource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, nil)}}
+	r.Create(ctx, resource.CreateRequest{Plan: plan}, createResp)
+	if createResp.Diagnostics.HasError() {
+		t.Fatalf("unexpected errors: %v", createResp.Diagnostics)
+	}
+
+	readResp := &resource.ReadResponse{State: createResp.State}
+	r.Read(ctx, resource.ReadRequest{State: createResp.State}, readResp)
+	if readResp.Diagnostics.HasError() || readResp.State.Raw.IsNull() {
+		t.Fatalf("expected the resource to be kept, got %v", readResp.Diagnostics)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("hello: tampered\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	readResp = &resource.ReadResponse{State: createResp.State}
+	r.Read(ctx, resource.ReadRequest{State: createResp.State}, readResp)
+	if !readResp.State.Raw.IsNull() {
+		t.Error("expected a modified file to be recreated")
+	}
+}
+
+func TestConvertCleartext(t *testing.T) {
+	converted, err := convertCleartext([]byte("hello: world\n"), "yaml", "dotenv")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(converted) != "hello=world\n" {
+		t.Errorf("unexpected dotenv output %q", converted)
+	}
+	if _, err := convertCleartext([]byte("hello: world\n"), "yaml", "raw"); err == nil {
+		t.Error("expected structured content not to convert to raw")
+	}
+}
@@ -1,202 +1,538 @@
 package sops
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	sops2 "go.mozilla.org/sops/v3"
-	"go.mozilla.org/sops/v3/aes"
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
-func resourceSourceFile() *schema.Resource {
-	return &schema.Resource{
-		Schema: map[string]*schema.Schema{
-			"filename": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
-			},
-			"encryption_type": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
-			},
-			"content": {
-				Type:     schema.TypeString,
-				Optional: true,
-				ForceNew: true,
-			},
-			"kms": {
-				Type:     schema.TypeMap,
-				Optional: true,
-				ForceNew: true,
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
+var (
+	_ resource.Resource                 = &resourceFile{}
+	_ resource.ResourceWithConfigure    = &resourceFile{}
+	_ resource.ResourceWithUpgradeState = &resourceFile{}
+	_ resource.ResourceWithModifyPlan   = &resourceFile{}
+)
+
+type resourceFile struct {
+	config *EncryptConfig
+}
+
+type resourceFileModel struct {
+	ID                    types.String        `tfsdk:"id"`
+	Filename              types.String        `tfsdk:"filename"`
+	EncryptionType        types.String        `tfsdk:"encryption_type"`
+	Content               types.String        `tfsdk:"content"`
+	ContentObject         types.Dynamic       `tfsdk:"content_object"`
+	Kms                   *resourceFileKms    `tfsdk:"kms"`
+	GcpKms                *resourceFileGcpKms `tfsdk:"gcpkms"`
+	Age                   *resourceFileAge    `tfsdk:"age"`
+	Pgp                   *resourceFilePgp    `tfsdk:"pgp"`
+	Azkv                  *resourceFileAzkv   `tfsdk:"azkv"`
+	Vault                 *resourceFileVault  `tfsdk:"vault"`
+	FilePermission        types.String        `tfsdk:"file_permission"`
+	DirectoryPermission   types.String        `tfsdk:"directory_permission"`
+	EncryptedRegex        types.String        `tfsdk:"encrypted_regex"`
+	Deterministic         types.Bool          `tfsdk:"deterministic"`
+	Owner                 types.String        `tfsdk:"owner"`
+	Group                 types.String        `tfsdk:"group"`
+	FollowSymlinks        types.Bool          `tfsdk:"follow_symlinks"`
+	KeepOnDestroy         types.Bool          `tfsdk:"keep_on_destroy"`
+	Shred                 types.Bool          `tfsdk:"shred"`
+	EncryptedSha256       types.String        `tfsdk:"encrypted_sha256"`
+	EncryptedBase64Sha256 types.String        `tfsdk:"encrypted_base64sha256"`
+	ContentSha256         types.String        `tfsdk:"content_sha256"`
+	LastModified          types.String        `tfsdk:"lastmodified"`
+	SopsVersion           types.String        `tfsdk:"sops_version"`
+	Recipients            types.List          `tfsdk:"recipients"`
+}
+
+type resourceFileKms struct {
+	ARN     types.String `tfsdk:"arn"`
+	Profile types.String `tfsdk:"profile"`
+	Role    types.String `tfsdk:"role"`
+	Context types.Map    `tfsdk:"context"`
+}
+
+type resourceFileGcpKms struct {
+	IDs         types.String `tfsdk:"ids"`
+	ResourceIDs types.List   `tfsdk:"resource_ids"`
+}
+
+type resourceFileAge struct {
+	Key            types.String `tfsdk:"key"`
+	Recipients     types.List   `tfsdk:"recipients"`
+	RecipientsFile types.String `tfsdk:"recipients_file"`
+	IdentityFile   types.String `tfsdk:"identity_file"`
+}
+
+type resourceFilePgp struct {
+	Fingerprints types.List `tfsdk:"fingerprints"`
+}
+
+type resourceFileAzkv struct {
+	URLs types.List `tfsdk:"urls"`
+}
+
+type resourceFileVault struct {
+	URIs types.List `tfsdk:"uris"`
+}
+
+func NewResourceFile() resource.Resource {
+	return &resourceFile{}
+}
+
+func (r *resourceFile) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_file"
+}
+
+func (r *resourceFile) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 3,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				Description:   "SHA-1 checksum of the encrypted file.",
+				PlanModifiers: []planmodifier.String{fileAttributeUseState{}},
+			},
+			"filename": schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"encryption_type": schema.StringAttribute{
+				Optional:           true,
+				Description:        "Restricts the keys used to kms, gcpkms, age or mix. When unset, every configured key is used.",
+				DeprecationMessage: "Remove encryption_type to encrypt with every configured key.",
+				Validators:         []validator.String{encryptionTypeValidator},
+				// Removing encryption_type must not recreate files written
+				// before it became optional; they are re-encrypted with
+				// every configured key on the next replacement
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplaceIf(
+					func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+						resp.RequiresReplace = !req.PlanValue.IsNull() && !deterministicPlan(ctx, req.Plan)
+					},
+					"Setting or changing encryption_type recreates the file, unless deterministic is set.",
+					"Setting or changing `encryption_type` recreates the file, unless `deterministic` is set.",
+				)},
+			},
+			"content": schema.StringAttribute{
+				Optional:      true,
+				PlanModifiers: []planmodifier.String{stringRequiresReplaceUnlessDeterministic()},
+			},
+			"content_object": schema.DynamicAttribute{
+				Optional:      true,
+				Sensitive:     true,
+				Description:   "Object to encrypt, written in the format of the filename extension with its keys sorted. Conflicts with content.",
+				PlanModifiers: []planmodifier.Dynamic{dynamicRequiresReplaceUnlessDeterministic()},
+			},
+			"kms": schema.SingleNestedAttribute{
+				Optional:      true,
+				Description:   "AWS KMS configuration.",
+				PlanModifiers: []planmodifier.Object{objectRequiresReplaceUnlessDeterministic()},
+				Attributes: map[string]schema.Attribute{
+					"arn": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["kms.arn"],
+						Validators:  []validator.String{kmsArnValidator},
+					},
+					"profile": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["kms.profile"],
+					},
+					"role": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["kms.role"],
+						Validators:  []validator.String{iamRoleArnValidator},
+					},
+					"context": schema.MapAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: providerDescriptions["kms.context"],
+					},
+				},
+			},
+			"gcpkms": schema.SingleNestedAttribute{
+				Optional:      true,
+				Description:   "GCP KMS configuration.",
+				PlanModifiers: []planmodifier.Object{objectRequiresReplaceUnlessDeterministic()},
+				Attributes: map[string]schema.Attribute{
+					"ids": schema.StringAttribute{
+						Optional:           true,
+						Description:        "Comma separated list of GCP KMS resource IDs.",
+						DeprecationMessage: "Use resource_ids instead.",
+						Validators:         []validator.String{gcpKmsResourceIDsValidator},
+					},
+					"resource_ids": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "Resource IDs of the GCP KMS keys to encrypt with.",
+						Validators:  []validator.List{gcpKmsResourceIDListValidator},
+					},
+				},
+			},
+			"age": schema.SingleNestedAttribute{
+				Optional:      true,
+				Description:   "Age configuration.",
+				PlanModifiers: []planmodifier.Object{objectRequiresReplaceUnlessDeterministic()},
+				Attributes: map[string]schema.Attribute{
+					"key": schema.StringAttribute{
+						Optional:           true,
+						Description:        providerDescriptions["age.key"],
+						DeprecationMessage: "Use recipients instead.",
+						Validators:         []validator.String{ageRecipientsValidator},
+					},
+					"recipients": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: providerDescriptions["age.recipients"],
+						Validators:  []validator.List{ageRecipientListValidator},
+					},
+					"recipients_file": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["age.recipients_file"],
+					},
+					"identity_file": schema.StringAttribute{
+						Optional:    true,
+						Description: providerDescriptions["age.identity_file"],
+					},
+				},
+			},
+			"pgp": schema.SingleNestedAttribute{
+				Optional:      true,
+				Description:   "PGP configuration.",
+				PlanModifiers: []planmodifier.Object{objectRequiresReplaceUnlessDeterministic()},
+				Attributes: map[string]schema.Attribute{
+					"fingerprints": schema.ListAttribute{
+						ElementType: types.StringType,
+						Required:    true,
+						Description: "Fingerprints of the PGP keys to encrypt for.",
+						Validators:  []validator.List{pgpFingerprintListValidator},
+					},
+				},
+			},
+			"azkv": schema.SingleNestedAttribute{
+				Optional:      true,
+				Description:   "Azure Key Vault configuration.",
+				PlanModifiers: []planmodifier.Object{objectRequiresReplaceUnlessDeterministic()},
+				Attributes: map[string]schema.Attribute{
+					"urls": schema.ListAttribute{
+						ElementType: types.StringType,
+						Required:    true,
+						Description: "URLs of the Azure Key Vault key versions to encrypt with.",
+						Validators:  []validator.List{azkvURLListValidator},
+					},
+				},
+			},
+			"vault": schema.SingleNestedAttribute{
+				Optional:      true,
+				Description:   "HashiCorp Vault configuration.",
+				PlanModifiers: []planmodifier.Object{objectRequiresReplaceUnlessDeterministic()},
+				Attributes: map[string]schema.Attribute{
+					"uris": schema.ListAttribute{
+						ElementType: types.StringType,
+						Required:    true,
+						Description: "URIs of the Vault transit keys to encrypt with.",
+						Validators:  []validator.List{vaultURIListValidator},
+					},
 				},
 			},
-			"gcpkms": {
-				Type:     schema.TypeMap,
-				Optional: true,
-				ForceNew: true,
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
+			"file_permission": schema.StringAttribute{
+				Description: "Permissions to set for the output file",
+				Optional:    true,
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					permissionDefault{value: "0600"},
+					stringRequiresReplaceUnlessDeterministic(),
 				},
+				Validators: []validator.String{fileModeValidator},
 			},
-			"age": {
-				Type:     schema.TypeMap,
-				Optional: true,
-				ForceNew: true,
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
+			"directory_permission": schema.StringAttribute{
+				Description: "Permissions to set for directories created",
+				Optional:    true,
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					permissionDefault{value: "0700"},
+					stringRequiresReplaceUnlessDeterministic(),
 				},
+				Validators: []validator.String{fileModeValidator},
+			},
+			"owner": schema.StringAttribute{
+				Description:   "Name or ID of the user to own the output file",
+				Optional:      true,
+				PlanModifiers: []planmodifier.String{stringRequiresReplaceUnlessDeterministic()},
+			},
+			"group": schema.StringAttribute{
+				Description:   "Name or ID of the group to own the output file",
+				Optional:      true,
+				PlanModifiers: []planmodifier.String{stringRequiresReplaceUnlessDeterministic()},
+			},
+			"follow_symlinks": schema.BoolAttribute{
+				Description: "Writes to the target of filename when it is a symbolic link, instead of failing",
+				Optional:    true,
+			},
+			"keep_on_destroy": schema.BoolAttribute{
+				Description: "Leaves the file on disk when the resource is destroyed",
+				Optional:    true,
 			},
-			"file_permission": {
-				Type:         schema.TypeString,
-				Description:  "Permissions to set for the output file",
-				Optional:     true,
-				ForceNew:     true,
-				Default:      "0777",
-				ValidateFunc: validateMode,
-			},
-			"directory_permission": {
-				Type:         schema.TypeString,
-				Description:  "Permissions to set for directories created",
-				Optional:     true,
-				ForceNew:     true,
-				Default:      "0777",
-				ValidateFunc: validateMode,
-			},
-			"encrypted_regex": {
-				Type:        schema.TypeString,
-				Description: "A regex pattern denoting the contents in the file to be encrypted",
+			"shred": schema.BoolAttribute{
+				Description: "Overwrites the file with random data before removing it on destroy",
+				Optional:    true,
+			},
+			"encrypted_sha256": schema.StringAttribute{
+				Computed:      true,
+				Description:   "SHA-256 checksum of the encrypted file, hex encoded.",
+				PlanModifiers: []planmodifier.String{fileAttributeUseState{}},
+			},
+			"encrypted_base64sha256": schema.StringAttribute{
+				Computed:      true,
+				Description:   "SHA-256 checksum of the encrypted file, base64 encoded.",
+				PlanModifiers: []planmodifier.String{fileAttributeUseState{}},
+			},
+			"content_sha256": schema.StringAttribute{
+				Computed:      true,
+				Description:   "SHA-256 checksum of the cleartext content, hex encoded.",
+				PlanModifiers: []planmodifier.String{fileAttributeUseState{}},
+			},
+			"lastmodified": schema.StringAttribute{
+				Computed:      true,
+				Description:   "Time the file was last encrypted, from its sops metadata.",
+				PlanModifiers: []planmodifier.String{fileAttributeUseState{}},
+			},
+			"sops_version": schema.StringAttribute{
+				Computed:      true,
+				Description:   "Version of sops recorded in the file metadata.",
+				PlanModifiers: []planmodifier.String{fileAttributeUseState{}},
+			},
+			"recipients": schema.ListAttribute{
+				ElementType:   types.StringType,
+				Computed:      true,
+				Description:   "Keys the file is encrypted for, from its sops metadata.",
+				PlanModifiers: []planmodifier.List{fileAttributeUseState{}},
+			},
+			"encrypted_regex": schema.StringAttribute{
+				Description:   "A regex pattern denoting the contents in the file to be encrypted",
+				Optional:      true,
+				PlanModifiers: []planmodifier.String{stringRequiresReplaceUnlessDeterministic()},
+				Validators:    []validator.String{regexValidator},
+			},
+			"deterministic": schema.BoolAttribute{
+				Description: "Keeps the file when its content and recipients didn't change, and otherwise reuses its data key and the ciphertext of its unchanged values. The other arguments are then updated in place.",
 				Optional:    true,
-				ForceNew:    true,
 			},
 		},
-		CreateContext: resourceSopsFileCreate,
-		Read:          resourceSopsFileRead,
-		Delete:        resourceSopsFileDelete,
 	}
-
 }
 
-func resourceSopsFileDelete(d *schema.ResourceData, _ interface{}) error {
-	os.Remove(d.Get("filename").(string))
-	return nil
+func (r *resourceFile) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	config, ok := req.ProviderData.(*EncryptConfig)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *EncryptConfig, got %T", req.ProviderData))
+		return
+	}
+	r.config = config
 }
 
-func resourceLocalFileContent(d *schema.ResourceData) ([]byte, error) {
-	if content, sensitiveSpecified := d.GetOk("sensitive_content"); sensitiveSpecified {
-		return []byte(content.(string)), nil
+func (r *resourceFile) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var model resourceFileModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	if b64Content, b64Specified := d.GetOk("content_base64"); b64Specified {
-		return base64.StdEncoding.DecodeString(b64Content.(string))
+	created, diags := r.writeFile(ctx, &model)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-
-	if v, ok := d.GetOk("source"); ok {
-		source := v.(string)
-		return ioutil.ReadFile(source)
+	if len(created) > 0 {
+		value, _ := json.Marshal(created)
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, createdDirectoriesKey, value)...)
 	}
-
-	content := d.Get("content")
-	return []byte(content.(string)), nil
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
 }
 
-func sopsEncrypt(d *schema.ResourceData, content []byte, config *EncryptConfig) ([]byte, error) {
-	inputStore := GetInputStore(d)
-	outputStore := GetOutputStore(d)
+// createdDirectoriesKey is the private state key of the directories created
+// for the file, which are removed with it when they are empty
+const createdDirectoriesKey = "created_directories"
 
-	encType := d.Get("encryption_type").(string)
-	fmt.Printf("enc type: %s\n", encType)
-
-	groups, err, bytes, err2 := getKeyGroups(d, encType, config)
-	if err2 != nil {
-		return bytes, err2
+// writeFile encrypts the content of model to its file and sets its ID. It
+// returns the directories it created, from the deepest one.
+func (r *resourceFile) writeFile(ctx context.Context, model *resourceFileModel) (created []string, diags diag.Diagnostics) {
+	config := r.config
+	if config == nil {
+		config = &EncryptConfig{}
 	}
-	encrypt, err := Encrypt(EncryptOpts{
-		Cipher:            aes.NewCipher(),
-		InputStore:        inputStore,
-		OutputStore:       outputStore,
-		InputPath:         d.Get("filename").(string),
-		KeyServices:       LocalKeySvc(),
-		UnencryptedSuffix: "",
-		EncryptedSuffix:   "",
-		UnencryptedRegex:  "",
-		EncryptedRegex:    d.Get("encrypted_regex").(string),
-		KeyGroups:         groups,
-		GroupThreshold:    0,
-	}, content)
-
-	if err != nil {
-		return nil, err
+	keyConf, keyDiags := model.keyConf(ctx)
+	diags.Append(keyDiags...)
+	if diags.HasError() {
+		return created, diags
 	}
-
+	branches, err := model.branches(ctx)
 	if err != nil {
-		return nil, err
+		diags.AddError("Invalid content", err.Error())
+		return created, diags
 	}
-	return encrypt, nil
-}
-
-func getKeyGroups(d *schema.ResourceData, encType string, config *EncryptConfig) ([]sops2.KeyGroup, error, []byte, error) {
-	groups, err := KeyGroups(d, encType, config)
+	content, err := sopsEncrypt(ctx, *model, keyConf, branches, config)
 	if err != nil {
-		return nil, nil, nil, err
+		diags.AddError("Failed to encrypt content", err.Error())
+		return created, diags
 	}
-	return groups, err, nil, nil
-}
 
-func resourceSopsFileCreate(ctx context.Context, d *schema.ResourceData, i interface{}) diag.Diagnostics {
-	providerConfig := i.(*EncryptConfig)
-	var diags diag.Diagnostics
-	content, err := resourceLocalFileContent(d)
+	destination, err := resolveSymlink(model.Filename.ValueString(), model.FollowSymlinks.ValueBool())
 	if err != nil {
-		return diag.FromErr(err)
+		diags.AddAttributeError(path.Root("filename"), "Refusing to write file", err.Error())
+		return created, diags
 	}
-	content, err = sopsEncrypt(d, content, providerConfig)
+	uid, gid, err := lookupOwner(model.Owner.ValueString(), model.Group.ValueString())
 	if err != nil {
-		return diag.FromErr(err)
+		diags.AddError("Invalid file owner", err.Error())
+		return created, diags
 	}
-	//content = encrypt
-	destination := d.Get("filename").(string)
-
-	destinationDir := path.Dir(destination)
+	destinationDir := filepath.Dir(destination)
 	if _, err := os.Stat(destinationDir); err != nil {
-		dirPerm := d.Get("directory_permission").(string)
-		dirMode, _ := strconv.ParseInt(dirPerm, 8, 64)
+		created = missingDirectories(destinationDir)
+		dirMode, _ := strconv.ParseInt(model.DirectoryPermission.ValueString(), 8, 64)
 		if err := os.MkdirAll(destinationDir, os.FileMode(dirMode)); err != nil {
-			return diag.FromErr(err)
+			diags.AddError("Failed to create directory", err.Error())
+			return created, diags
 		}
 	}
 
-	filePerm := d.Get("file_permission").(string)
-	fileMode, _ := strconv.ParseInt(filePerm, 8, 64)
+	fileMode, _ := strconv.ParseInt(model.FilePermission.ValueString(), 8, 64)
+	if existing, err := ioutil.ReadFile(destination); err == nil && bytes.Equal(existing, content) {
+		// deterministic kept the file as it is
+		if diags.Append(setFileOwnership(destination, os.FileMode(fileMode), uid, gid)...); diags.HasError() {
+			return created, diags
+		}
+	} else if err := writeFileAtomicOwner(destination, content, os.FileMode(fileMode), uid, gid); err != nil {
+		diags.AddError("Failed to write file", err.Error())
+		return created, diags
+	}
+
+	diags.Append(model.setFileAttributes(ctx, content)...)
+	return created, diags
+}
+
+// updateFileOwnership applies the permission, owner and group of m to the
+// file it wrote, for deterministic updates that leave its content as it is
+func (m resourceFileModel) updateFileOwnership() (diags diag.Diagnostics) {
+	destination, err := resolveSymlink(m.Filename.ValueString(), m.FollowSymlinks.ValueBool())
+	if err != nil {
+		diags.AddAttributeError(path.Root("filename"), "Refusing to update file", err.Error())
+		return diags
+	}
+	uid, gid, err := lookupOwner(m.Owner.ValueString(), m.Group.ValueString())
+	if err != nil {
+		diags.AddError("Invalid file owner", err.Error())
+		return diags
+	}
+	fileMode, _ := strconv.ParseInt(m.FilePermission.ValueString(), 8, 64)
+	return setFileOwnership(destination, os.FileMode(fileMode), uid, gid)
+}
 
-	if err := ioutil.WriteFile(destination, content, os.FileMode(fileMode)); err != nil {
-		return diag.FromErr(err)
+// setFileOwnership sets the mode of filename, and its owner and group unless
+// they are -1
+func setFileOwnership(filename string, mode os.FileMode, uid, gid int) (diags diag.Diagnostics) {
+	if err := os.Chmod(filename, mode); err != nil {
+		diags.AddError("Failed to set file permission", err.Error())
+		return diags
+	}
+	if uid != -1 || gid != -1 {
+		if err := os.Lchown(filename, uid, gid); err != nil {
+			diags.AddError("Failed to set file owner", err.Error())
+		}
 	}
+	return diags
+}
 
+// setFileAttributes sets the computed attributes describing content, the
+// encrypted file written for m
+func (m *resourceFileModel) setFileAttributes(ctx context.Context, content []byte) diag.Diagnostics {
+	var diags diag.Diagnostics
 	checksum := sha1.Sum(content)
-	d.SetId(hex.EncodeToString(checksum[:]))
+	m.ID = types.StringValue(hex.EncodeToString(checksum[:]))
+	checksum256 := sha256.Sum256(content)
+	m.EncryptedSha256 = types.StringValue(hex.EncodeToString(checksum256[:]))
+	m.EncryptedBase64Sha256 = types.StringValue(base64.StdEncoding.EncodeToString(checksum256[:]))
 
+	cleartext := []byte(m.Content.ValueString())
+	if !m.ContentObject.IsNull() {
+		branches, err := m.branches(ctx)
+		if err == nil {
+			cleartext, err = GetOutputStore(m.Filename.ValueString()).EmitPlainFile(branches)
+		}
+		if err != nil {
+			diags.AddAttributeError(path.Root("content_object"), "Invalid content", err.Error())
+			return diags
+		}
+	}
+	m.ContentSha256 = types.StringValue(sha256Hex(cleartext))
+
+	tree, err := GetOutputStore(m.Filename.ValueString()).LoadEncryptedFile(content)
+	if err != nil {
+		diags.AddError("Failed to read sops metadata", err.Error())
+		return diags
+	}
+	m.LastModified = types.StringValue(tree.Metadata.LastModified.Format(time.RFC3339))
+	m.SopsVersion = types.StringValue(tree.Metadata.Version)
+	var recipients []string
+	for _, group := range tree.Metadata.KeyGroups {
+		recipients = append(recipients, masterKeyStrings(group)...)
+	}
+	var listDiags diag.Diagnostics
+	m.Recipients, listDiags = types.ListValueFrom(ctx, types.StringType, recipients)
+	diags.Append(listDiags...)
 	return diags
+}
 
+// copyFileAttributes copies the computed attributes of prior, for updates
+// that leave the file as it is
+func (m *resourceFileModel) copyFileAttributes(prior resourceFileModel) {
+	m.ID = prior.ID
+	m.EncryptedSha256 = prior.EncryptedSha256
+	m.EncryptedBase64Sha256 = prior.EncryptedBase64Sha256
+	m.ContentSha256 = prior.ContentSha256
+	m.LastModified = prior.LastModified
+	m.SopsVersion = prior.SopsVersion
+	m.Recipients = prior.Recipients
 }
 
-func resourceSopsFileRead(d *schema.ResourceData, i interface{}) error {
+func (r *resourceFile) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var model resourceFileModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// If the output file doesn't exist, mark the resource for creation.
-	outputPath := d.Get("filename").(string)
+	outputPath := model.Filename.ValueString()
 	if _, err := os.Stat(outputPath); os.IsNotExist(err) {
-		d.SetId("")
-		return nil
+		resp.State.RemoveResource(ctx)
+		return
 	}
 
 	// Verify that the content of the destination file matches the content we
@@ -204,35 +540,206 @@ func resourceSopsFileRead(d *schema.ResourceData, i interface{}) error {
 	// must reconcile.
 	outputContent, err := ioutil.ReadFile(outputPath)
 	if err != nil {
-		return err
+		resp.Diagnostics.AddError("Failed to read file", err.Error())
+		return
 	}
 
 	outputChecksum := sha1.Sum(outputContent)
-	if hex.EncodeToString(outputChecksum[:]) != d.Id() {
-		d.SetId("")
-		return nil
+	if hex.EncodeToString(outputChecksum[:]) != model.ID.ValueString() {
+		resp.State.RemoveResource(ctx)
+		return
 	}
 
-	return nil
+	// Fill in the attributes of files written before they were added
+	resp.Diagnostics.Append(model.setFileAttributes(ctx, outputContent)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
 }
 
-func validateMode(i interface{}, k string) (s []string, es []error) {
-	v, ok := i.(string)
+func (r *resourceFile) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var model resourceFileModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if planRewritesFile(ctx, req.State, req.Plan) {
+		created, diags := r.writeFile(ctx, &model)
+		resp.Diagnostics.Append(diags...)
+		if len(created) > 0 {
+			// The directories were removed since the file was created
+			value, _ := json.Marshal(created)
+			resp.Diagnostics.Append(resp.Private.SetKey(ctx, createdDirectoriesKey, value)...)
+		}
+	} else {
+		// Without deterministic, every argument requires replacement, except
+		// removing encryption_type and the arguments only used on write or
+		// destroy. Both leave the file as it is, so the plan can keep the
+		// attributes describing it.
+		if model.Deterministic.ValueBool() {
+			resp.Diagnostics.Append(model.updateFileOwnership()...)
+		}
+		var prior resourceFileModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+		model.copyFileAttributes(prior)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
 
-	if !ok {
-		es = append(es, fmt.Errorf("expected type of %s to be string", k))
+func (r *resourceFile) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var model resourceFileModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if model.KeepOnDestroy.ValueBool() {
+		tflog.Debug(ctx, "Keeping file", map[string]interface{}{"filename": model.Filename.ValueString()})
 		return
 	}
 
+	filename := model.Filename.ValueString()
+	if model.FollowSymlinks.ValueBool() {
+		// The file was written to the target of the link, which is removed
+		// instead of the link itself. A dangling link has nothing to remove.
+		target, err := resolveSymlink(filename, true)
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to resolve symbolic link", err.Error())
+			return
+		}
+		filename = target
+	}
+	if model.Shred.ValueBool() {
+		if err := shredFile(filename); err != nil && !os.IsNotExist(err) {
+			resp.Diagnostics.AddError("Failed to shred file", err.Error())
+			return
+		}
+	}
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		resp.Diagnostics.AddError("Failed to remove file", err.Error())
+		return
+	}
+
+	value, diags := req.Private.GetKey(ctx, createdDirectoriesKey)
+	resp.Diagnostics.Append(diags...)
+	var created []string
+	if len(value) > 0 {
+		if err := json.Unmarshal(value, &created); err != nil {
+			resp.Diagnostics.AddWarning("Failed to read the created directories", err.Error())
+			return
+		}
+	}
+	removeEmptyDirectories(created)
+}
+
+// keyConf returns the keys configured on the resource
+func (m resourceFileModel) keyConf(ctx context.Context) (KeyConf, diag.Diagnostics) {
+	var conf KeyConf
+	var diags diag.Diagnostics
+	if m.Kms != nil {
+		conf.Kms = &KmsConf{
+			ARN:     m.Kms.ARN.ValueString(),
+			Profile: m.Kms.Profile.ValueString(),
+			Role:    m.Kms.Role.ValueString(),
+		}
+		diags.Append(m.Kms.Context.ElementsAs(ctx, &conf.Kms.Context, true)...)
+	}
+	if m.Age != nil {
+		diags.Append(m.Age.Recipients.ElementsAs(ctx, &conf.Age, true)...)
+		conf.Age = append(conf.Age, splitList(m.Age.Key.ValueString())...)
+		recipients, fileDiags := ageFileRecipients(path.Root("age"), m.Age.RecipientsFile, m.Age.IdentityFile)
+		diags.Append(fileDiags...)
+		conf.Age = append(conf.Age, recipients...)
+	}
+	if m.GcpKms != nil {
+		diags.Append(m.GcpKms.ResourceIDs.ElementsAs(ctx, &conf.GcpKms, true)...)
+		conf.GcpKms = append(conf.GcpKms, splitList(m.GcpKms.IDs.ValueString())...)
+	}
+	if m.Pgp != nil {
+		diags.Append(m.Pgp.Fingerprints.ElementsAs(ctx, &conf.Pgp, true)...)
+	}
+	if m.Azkv != nil {
+		diags.Append(m.Azkv.URLs.ElementsAs(ctx, &conf.Azkv, true)...)
+	}
+	if m.Vault != nil {
+		diags.Append(m.Vault.URIs.ElementsAs(ctx, &conf.Vault, true)...)
+	}
+	return conf, diags
+}
+
+// branches parses content, or converts content_object, into the tree to
+// encrypt
+func (m resourceFileModel) branches(ctx context.Context) (mozillasops.TreeBranches, error) {
+	if !m.ContentObject.IsNull() {
+		return contentObjectBranches(ctx, m.ContentObject)
+	}
+	return GetInputStore(m.Filename.ValueString()).LoadPlainFile([]byte(m.Content.ValueString()))
+}
+
+// splitList splits a comma separated list, ignoring empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func sopsEncrypt(ctx context.Context, model resourceFileModel, keyConf KeyConf, branches mozillasops.TreeBranches, config *EncryptConfig) ([]byte, error) {
+	filename := model.Filename.ValueString()
+	inputStore := GetInputStore(filename)
+	outputStore := GetOutputStore(filename)
+
+	encType := model.EncryptionType.ValueString()
+	ctx = withLogSubsystem(ctx, logEncrypt)
+	tflog.SubsystemDebug(ctx, logEncrypt, "Encrypting file", map[string]interface{}{"filename": filename, "encryption_type": encType})
+
+	groups, err := KeyGroups(ctx, keyConf, encType, config)
+	if err != nil {
+		return nil, err
+	}
+	opts := EncryptOpts{
+		Cipher:            aes.NewCipher(),
+		InputStore:        inputStore,
+		OutputStore:       outputStore,
+		InputPath:         filename,
+		KeyServices:       configKeySvc(ctx, config),
+		UnencryptedSuffix: "",
+		EncryptedSuffix:   "",
+		UnencryptedRegex:  "",
+		EncryptedRegex:    model.EncryptedRegex.ValueString(),
+		KeyGroups:         groups,
+		GroupThreshold:    0,
+	}
+	if model.Deterministic.ValueBool() {
+		// The tree is loaded again for every copy deterministicEncrypt needs
+		reload := func() (mozillasops.TreeBranches, error) {
+			return model.branches(ctx)
+		}
+		content, ok, err := deterministicEncrypt(ctx, filename, opts, reload)
+		if err != nil || ok {
+			return content, err
+		}
+	}
+	return encryptBranches(ctx, opts, branches)
+}
+
+func validateMode(v string) error {
 	if len(v) > 4 || len(v) < 3 {
-		es = append(es, fmt.Errorf("bad mode for file - string length should be 3 or 4 digits: %s", v))
+		return fmt.Errorf("bad mode for file - string length should be 3 or 4 digits: %s", v)
 	}
 
 	fileMode, err := strconv.ParseInt(v, 8, 64)
-
 	if err != nil || fileMode > 0777 || fileMode < 0 {
-		es = append(es, fmt.Errorf("bad mode for file - must be three octal digits: %s", v))
+		return fmt.Errorf("bad mode for file - must be three octal digits: %s", v)
 	}
-
-	return
+	return nil
 }
@@ -0,0 +1,346 @@
+package sops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ resource.Resource                = &resourceFileKey{}
+	_ resource.ResourceWithImportState = &resourceFileKey{}
+)
+
+// resourceFileKey manages a single value of an existing encrypted file, so
+// that several configurations can contribute to the same file
+type resourceFileKey struct{}
+
+type resourceFileKeyModel struct {
+	ID        types.String `tfsdk:"id"`
+	Filename  types.String `tfsdk:"filename"`
+	InputType types.String `tfsdk:"input_type"`
+	Document  types.Int64  `tfsdk:"document"`
+	Path      types.List   `tfsdk:"path"`
+	Value     types.String `tfsdk:"value"`
+	ValueType types.String `tfsdk:"value_type"`
+}
+
+func NewResourceFileKey() resource.Resource {
+	return &resourceFileKey{}
+}
+
+func (r *resourceFileKey) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_file_key"
+}
+
+func (r *resourceFileKey) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Sets a single value in an existing sops-encrypted file.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				Description:   "Filename, document and path of the value, as filename:document:path.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"filename": schema.StringAttribute{
+				Required:      true,
+				Description:   "Path to the encrypted file.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"input_type": schema.StringAttribute{
+				Optional:      true,
+				Description:   "Format of the encrypted file: json, yaml, dotenv, ini or auto. Defaults to the format of the filename extension.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"document": schema.Int64Attribute{
+				Optional:      true,
+				Description:   "Index of the YAML document holding the value, in multi-document files. Defaults to 0, the first document.",
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+				Validators:    []validator.Int64{int64AtLeastValidator{min: 0}},
+			},
+			"path": schema.ListAttribute{
+				ElementType:   types.StringType,
+				Required:      true,
+				Description:   "Keys leading to the value. List elements are indexed with their position.",
+				PlanModifiers: []planmodifier.List{listplanmodifier.RequiresReplace()},
+				Validators:    []validator.List{minItemsValidator{min: 1}},
+			},
+			"value": schema.StringAttribute{
+				Required:    true,
+				Sensitive:   true,
+				Description: "Value to set.",
+			},
+			"value_type": schema.StringAttribute{
+				Optional:    true,
+				Description: "How value is written: string, or json to decode it like sops --set. Defaults to keeping the type of an existing number or boolean.",
+				Validators:  []validator.String{valueTypeValidator},
+			},
+		},
+	}
+}
+
+func (r *resourceFileKey) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var model resourceFileKeyModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// A value set before belongs to someone else, and would be unset on
+	// destroy: it must be imported to be managed
+	if err := model.set(ctx, model.keys(), false); err != nil {
+		resp.Diagnostics.AddError("Failed to set value", err.Error())
+		return
+	}
+	model.ID = types.StringValue(model.id())
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r *resourceFileKey) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var model resourceFileKeyModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	file, err := loadEncryptedFile(ctx, model.Filename.ValueString(), model.InputType.ValueString())
+	if os.IsNotExist(err) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read file", err.Error())
+		return
+	}
+	document := model.document()
+	if document >= len(file.tree.Branches) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	value, ok := treeGet(file.tree.Branches[document], model.keys())
+	if !ok {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	// IDs used to leave the document out
+	model.ID = types.StringValue(model.id())
+	if model.ValueType.ValueString() == "json" {
+		current, err := storeForFormat(formats.Json).EmitValue(value)
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to encode value", err.Error())
+			return
+		}
+		// Keep the configured formatting when the value didn't change
+		if !jsonEqual(current, []byte(model.Value.ValueString())) {
+			model.Value = types.StringValue(string(current))
+		}
+		resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+		return
+	}
+	switch value.(type) {
+	case mozillasops.TreeBranch, []interface{}:
+		// The value was replaced by a map or a list, which this resource
+		// doesn't manage
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	model.Value = types.StringValue(fmt.Sprint(value))
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r *resourceFileKey) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var model resourceFileKeyModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := model.set(ctx, model.keys(), true); err != nil {
+		resp.Diagnostics.AddError("Failed to set value", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r *resourceFileKey) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var model resourceFileKeyModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	keys := model.keys()
+	err := editEncryptedFile(ctx, model.Filename.ValueString(), model.InputType.ValueString(), model.document(), func(branch mozillasops.TreeBranch) (mozillasops.TreeBranch, error) {
+		unset, found := treeUnset(branch, keys)
+		if !found {
+			return branch, errKeyNotFound
+		}
+		return treePrune(unset.(mozillasops.TreeBranch), keys), nil
+	})
+	// The value is already gone when the file or key was removed externally
+	if err != nil && !os.IsNotExist(err) && !errors.Is(err, errKeyNotFound) {
+		resp.Diagnostics.AddError("Failed to unset value", err.Error())
+	}
+}
+
+// ImportState manages a value already set in a file, from an ID formatted
+// like the id attribute
+func (r *resourceFileKey) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	filename, document, keys, err := parseFileKeyID(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid import ID", err.Error())
+		return
+	}
+	keysValue, diags := types.ListValueFrom(ctx, types.StringType, keys)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	model := resourceFileKeyModel{
+		ID:        types.StringValue(req.ID),
+		Path:      keysValue,
+		Filename:  types.StringValue(filename),
+		InputType: types.StringNull(),
+		Document:  types.Int64Null(),
+		Value:     types.StringNull(),
+		ValueType: types.StringNull(),
+	}
+	if document > 0 {
+		model.Document = types.Int64Value(int64(document))
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+var errKeyNotFound = errors.New("key not found")
+
+// fileKeyIDPattern matches filename:document:path IDs. The filename ends at
+// the first document index, so that keys may hold colons.
+var fileKeyIDPattern = regexp.MustCompile(`^(.+?):(\d+):(.+)$`)
+
+// id returns the ID of m: its filename, document and path, with the dots and
+// backslashes of the keys escaped
+func (m resourceFileKeyModel) id() string {
+	escaper := newFlattener(flattenOptions{Separator: ".", EscapeKeys: true})
+	keys := m.keys()
+	for i, key := range keys {
+		keys[i] = escaper.escape(key)
+	}
+	return fmt.Sprintf("%s:%d:%s", m.Filename.ValueString(), m.document(), strings.Join(keys, "."))
+}
+
+// parseFileKeyID splits an ID returned by id
+func parseFileKeyID(id string) (string, int, []string, error) {
+	match := fileKeyIDPattern.FindStringSubmatch(id)
+	if match == nil {
+		return "", 0, nil, fmt.Errorf("expected an ID formatted as filename:document:path, such as secrets.enc.yaml:0:database.password, got %q", id)
+	}
+	document, err := strconv.Atoi(match[2])
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("invalid document %q: %s", match[2], err)
+	}
+	var keys []string
+	var key strings.Builder
+	escaped := false
+	for _, c := range match[3] {
+		switch {
+		case escaped:
+			key.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '.':
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteRune(c)
+		}
+	}
+	return match[1], document, append(keys, key.String()), nil
+}
+
+func (m resourceFileKeyModel) keys() []string {
+	keys := make([]string, 0, len(m.Path.Elements()))
+	for _, element := range m.Path.Elements() {
+		if s, ok := element.(types.String); ok {
+			keys = append(keys, s.ValueString())
+		}
+	}
+	return keys
+}
+
+// document returns the index of the document holding the value of m
+func (m resourceFileKeyModel) document() int {
+	return int(m.Document.ValueInt64())
+}
+
+// set writes the value of m at keys in its file. Unless replace is set, a
+// value already at keys is an error.
+func (m resourceFileKeyModel) set(ctx context.Context, keys []string, replace bool) error {
+	return editEncryptedFile(ctx, m.Filename.ValueString(), m.InputType.ValueString(), m.document(), func(branch mozillasops.TreeBranch) (mozillasops.TreeBranch, error) {
+		existing, found := treeGet(branch, keys)
+		if found && !replace {
+			return nil, fmt.Errorf("%s is already set in %s. Import it to manage it with this resource, using the ID %s", strings.Join(keys, "."), m.Filename.ValueString(), m.id())
+		}
+		value, err := m.typedValue(existing)
+		if err != nil {
+			return nil, fmt.Errorf("can't set %s: %s", strings.Join(keys, "."), err)
+		}
+		set, err := treeSet(branch, keys, value)
+		if err != nil {
+			return nil, fmt.Errorf("can't set %s: %s", strings.Join(keys, "."), err)
+		}
+		return set.(mozillasops.TreeBranch), nil
+	})
+}
+
+// typedValue converts the value of m to the value written in place of
+// existing, which is nil for a new key
+func (m resourceFileKeyModel) typedValue(existing interface{}) (interface{}, error) {
+	value := m.Value.ValueString()
+	switch m.ValueType.ValueString() {
+	case "json":
+		return jsonTreeValue(value)
+	case "string":
+		return value, nil
+	}
+	// Numbers and booleans keep their type as long as the value still has it,
+	// so editing a port doesn't turn it into a string
+	switch existing.(type) {
+	case bool:
+		if value == "true" || value == "false" {
+			return value == "true", nil
+		}
+	case int, float64:
+		if typed, err := jsonTreeValue(value); err == nil {
+			switch typed.(type) {
+			case int, float64:
+				return typed, nil
+			}
+		}
+	}
+	return value, nil
+}
+
+// jsonEqual reports whether a and b are the same JSON value, regardless of
+// their formatting and key order
+func jsonEqual(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,408 @@
+package sops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	mozillasops "github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/getsops/sops/v3/pgp"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	sdkresource "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const configTestResourceSopsFileKey_basic = `
+resource "sops_file_key" "password" {
+  filename = "%s"
+  path     = ["db", "password"]
+  value    = "%s"
+}`
+
+func TestResourceSopsFileKey_basic(t *testing.T) {
+	filename := testCopyFixture(t, "basic.yaml")
+	sdkresource.UnitTest(t, sdkresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testCheckFileKey(filename, []string{"db", "password"}, ""),
+		Steps: []sdkresource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFileKey_basic, filename, "s3cr3t"),
+				Check: sdkresource.ComposeTestCheckFunc(
+					testCheckFileKey(filename, []string{"db", "password"}, "s3cr3t"),
+					testCheckFileKey(filename, []string{"hello"}, "world"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFileKey_basic, filename, "changed"),
+				Check:  testCheckFileKey(filename, []string{"db", "password"}, "changed"),
+			},
+		},
+	})
+}
+
+// testCopyFixture copies a test fixture to a temporary directory, so that
+// tests can edit it
+func testCopyFixture(t *testing.T, name string) string {
+	t.Helper()
+	content, err := ioutil.ReadFile(filepath.Join("test-fixtures", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// testCheckFileKey checks the value at keys in filename, or its absence
+// when expected is empty
+func testCheckFileKey(filename string, keys []string, expected string) sdkresource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		file, err := loadEncryptedFile(context.Background(), filename, "")
+		if err != nil {
+			return err
+		}
+		value, ok := treeGet(file.tree.Branches[0], keys)
+		if expected == "" {
+			if ok {
+				return fmt.Errorf("expected %s to be unset in %s, got %v", strings.Join(keys, "."), filename, value)
+			}
+			return nil
+		}
+		if !ok || fmt.Sprint(value) != expected {
+			return fmt.Errorf("expected %s to be %q in %s, got %v", strings.Join(keys, "."), expected, filename, value)
+		}
+		return nil
+	}
+}
+
+func testResourceFileKeyPlan(t *testing.T, r resource.Resource, filename, value, valueType string, keys ...string) tfsdk.Plan {
+	t.Helper()
+	ctx := context.Background()
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	elements := make([]tftypes.Value, len(keys))
+	for i, key := range keys {
+		elements[i] = tftypes.NewValue(tftypes.String, key)
+	}
+	values := map[string]tftypes.Value{
+		"id":         tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+		"filename":   tftypes.NewValue(tftypes.String, filename),
+		"input_type": tftypes.NewValue(tftypes.String, nil),
+		"document":   tftypes.NewValue(tftypes.Number, nil),
+		"path":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, elements),
+		"value":      tftypes.NewValue(tftypes.String, value),
+		"value_type": tftypes.NewValue(tftypes.String, nil),
+	}
+	if valueType != "" {
+		values["value_type"] = tftypes.NewValue(tftypes.String, valueType)
+	}
+	return tfsdk.Plan{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objType, values)}
+}
+
+// testEncryptedFile writes cleartext encrypted with the test PGP key to a
+// temporary file named name
+func testEncryptedFile(t *testing.T, name, cleartext string, perm os.FileMode) string {
+	t.Helper()
+	ctx := context.Background()
+	store := storeForFormat(formats.FormatForPath(name))
+	encrypted, err := Encrypt(ctx, EncryptOpts{
+		Cipher:      aes.NewCipher(),
+		InputStore:  store,
+		OutputStore: store,
+		InputPath:   name,
+		KeyServices: LocalKeySvc(ctx),
+		KeyGroups:   []mozillasops.KeyGroup{{pgp.NewMasterKeyFromFingerprint(testPgpFingerprint)}},
+	}, []byte(cleartext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(filename, encrypted, perm); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestResourceSopsFileKey_preserve(t *testing.T) {
+	ctx := context.Background()
+	filename := testEncryptedFile(t, "shared.yaml", "# shared secrets\nhello: world # greeting\nservers:\n  - web\n", 0640)
+	before, err := loadEncryptedFile(ctx, filename, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewResourceFileKey()
+	plan := testResourceFileKeyPlan(t, r, filename, "s3cr3t", "", "servers", "1", "password")
+	createResp := &resource.CreateResponse{State: tfsdk.State{Schema: plan.Schema, Raw: tftypes.NewValue(plan.Raw.Type(), nil)}}
+	r.Create(ctx, resource.CreateRequest{Plan: plan}, createResp)
+	if createResp.Diagnostics.HasError() {
+		t.Fatalf("unexpected errors: %v", createResp.Diagnostics)
+	}
+
+	after, err := loadEncryptedFile(ctx, filename, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after.dataKey) != string(before.dataKey) {
+		t.Error("expected the data key to be kept")
+	}
+	decrypted, err := after.store.EmitPlainFile(after.tree.Branches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sops moves trailing comments above the value they follow
+	expected := "# shared secrets\n# greeting\nhello: world\nservers:\n    - web\n    - password: s3cr3t\n"
+	if string(decrypted) != expected {
+		t.Errorf("expected the other values and comments to be kept, got %q", decrypted)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("expected the file mode to be kept, got %o", info.Mode().Perm())
+	}
+
+	readResp := &resource.ReadResponse{State: createResp.State}
+	r.Read(ctx, resource.ReadRequest{State: createResp.State}, readResp)
+	if readResp.Diagnostics.HasError() || readResp.State.Raw.IsNull() {
+		t.Fatalf("expected the resource to be kept, got %v", readResp.Diagnostics)
+	}
+
+	deleteResp := &resource.DeleteResponse{State: createResp.State}
+	r.Delete(ctx, resource.DeleteRequest{State: createResp.State}, deleteResp)
+	if deleteResp.Diagnostics.HasError() {
+		t.Fatalf("unexpected errors: %v", deleteResp.Diagnostics)
+	}
+	if err := testCheckFileKey(filename, []string{"servers", "1", "password"}, "")(nil); err != nil {
+		t.Error(err)
+	}
+	if err := testCheckFileKey(filename, []string{"hello"}, "world")(nil); err != nil {
+		t.Error(err)
+	}
+	if err := testCheckFileKey(filename, []string{"servers", "1"}, "")(nil); err != nil {
+		t.Errorf("expected the map created for the value to be removed: %s", err)
+	}
+
+	readResp = &resource.ReadResponse{State: createResp.State}
+	r.Read(ctx, resource.ReadRequest{State: createResp.State}, readResp)
+	if !readResp.State.Raw.IsNull() {
+		t.Error("expected an unset value to be recreated")
+	}
+}
+
+func TestResourceSopsFileKey_typed(t *testing.T) {
+	ctx := context.Background()
+	filename := testEncryptedFile(t, "typed.yaml", "port: 8080\nenabled: true\nname: web\n", 0600)
+	r := NewResourceFileKey()
+	create := func(value, valueType string, keys ...string) tfsdk.State {
+		t.Helper()
+		plan := testResourceFileKeyPlan(t, r, filename, value, valueType, keys...)
+		resp := &resource.CreateResponse{State: tfsdk.State{Schema: plan.Schema, Raw: tftypes.NewValue(plan.Raw.Type(), nil)}}
+		r.Create(ctx, resource.CreateRequest{Plan: plan}, resp)
+		if resp.Diagnostics.HasError() {
+			t.Fatalf("unexpected errors: %v", resp.Diagnostics)
+		}
+		return resp.State
+	}
+	update := func(value string, keys ...string) {
+		t.Helper()
+		plan := testResourceFileKeyPlan(t, r, filename, value, "", keys...)
+		resp := &resource.UpdateResponse{State: tfsdk.State{Schema: plan.Schema, Raw: tftypes.NewValue(plan.Raw.Type(), nil)}}
+		r.Update(ctx, resource.UpdateRequest{Plan: plan}, resp)
+		if resp.Diagnostics.HasError() {
+			t.Fatalf("unexpected errors: %v", resp.Diagnostics)
+		}
+	}
+	update("9090", "port")
+	update("false", "enabled")
+	update("1234", "name")
+	create("8443", "string", "tls_port")
+	state := create(`{"b": [1, "two"], "a": true}`, "json", "extra")
+
+	file, err := loadEncryptedFile(ctx, filename, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := file.store.EmitPlainFile(file.tree.Branches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "port: 9090\nenabled: false\nname: \"1234\"\ntls_port: \"8443\"\nextra:\n    b:\n        - 1\n        - two\n    a: true\n"
+	if string(decrypted) != expected {
+		t.Errorf("expected numbers and booleans to keep their type, got %q", decrypted)
+	}
+
+	readResp := &resource.ReadResponse{State: state}
+	r.Read(ctx, resource.ReadRequest{State: state}, readResp)
+	if readResp.Diagnostics.HasError() {
+		t.Fatalf("unexpected errors: %v", readResp.Diagnostics)
+	}
+	var model resourceFileKeyModel
+	readResp.Diagnostics.Append(readResp.State.Get(ctx, &model)...)
+	if model.Value.ValueString() != `{"b": [1, "two"], "a": true}` {
+		t.Errorf("expected the configured JSON to be kept, got %q", model.Value.ValueString())
+	}
+
+	if _, err := (resourceFileKeyModel{Value: types.StringValue("{"), ValueType: types.StringValue("json")}).typedValue(nil); err == nil {
+		t.Error("expected invalid JSON to be rejected")
+	}
+}
+
+func TestEditEncryptedFile_document(t *testing.T) {
+	ctx := context.Background()
+	filename := testEncryptedFile(t, "bundle.yaml", "name: first\n---\nname: second\n", 0600)
+	err := editEncryptedFile(ctx, filename, "", 1, func(branch mozillasops.TreeBranch) (mozillasops.TreeBranch, error) {
+		set, err := treeSet(branch, []string{"name"}, "edited")
+		return set.(mozillasops.TreeBranch), err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	file, err := loadEncryptedFile(ctx, filename, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{"first", "edited"} {
+		if value, _ := treeGet(file.tree.Branches[i], []string{"name"}); value != expected {
+			t.Errorf("expected document %d to be %q, got %v", i, expected, value)
+		}
+	}
+
+	err = editEncryptedFile(ctx, filename, "", 2, func(branch mozillasops.TreeBranch) (mozillasops.TreeBranch, error) {
+		return branch, nil
+	})
+	if !errors.Is(err, errKeyNotFound) {
+		t.Errorf("expected a missing document to be reported, got %v", err)
+	}
+}
+
+func TestTreeSet(t *testing.T) {
+	branch := mozillasops.TreeBranch{{Key: "hello", Value: "world"}}
+	if _, err := treeSet(branch, []string{"hello", "nested"}, "value"); err == nil {
+		t.Error("expected setting a key in a string to fail")
+	}
+	list := mozillasops.TreeBranch{{Key: "list", Value: []interface{}{"a"}}}
+	if _, err := treeSet(list, []string{"list", "2"}, "c"); err == nil {
+		t.Error("expected setting past the end of a list to fail")
+	}
+	set, err := treeSet(list, []string{"list", "1"}, "b")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if value, ok := treeGet(set, []string{"list", "1"}); !ok || value != "b" {
+		t.Errorf("expected the value to be appended, got %v", set)
+	}
+}
+
+func TestResourceSopsFileKey_import(t *testing.T) {
+	ctx := context.Background()
+	filename := testEncryptedFile(t, "existing.yaml", "db:\n  password: old\nhello: world\n", 0600)
+	r := NewResourceFileKey()
+	plan := testResourceFileKeyPlan(t, r, filename, "new", "", "db", "password")
+	createResp := &resource.CreateResponse{State: tfsdk.State{Schema: plan.Schema, Raw: tftypes.NewValue(plan.Raw.Type(), nil)}}
+	r.Create(ctx, resource.CreateRequest{Plan: plan}, createResp)
+	if !createResp.Diagnostics.HasError() {
+		t.Fatal("expected an existing value to be refused")
+	}
+	if detail := createResp.Diagnostics[0].Detail(); !strings.Contains(detail, filename+":0:db.password") {
+		t.Errorf("expected the error to give the import ID, got %q", detail)
+	}
+	if err := testCheckFileKey(filename, []string{"db", "password"}, "old")(nil); err != nil {
+		t.Error(err)
+	}
+
+	importResp := &resource.ImportStateResponse{State: tfsdk.State{Schema: plan.Schema, Raw: tftypes.NewValue(plan.Raw.Type(), nil)}}
+	r.(resource.ResourceWithImportState).ImportState(ctx, resource.ImportStateRequest{ID: filename + ":0:db.password"}, importResp)
+	if importResp.Diagnostics.HasError() {
+		t.Fatalf("unexpected errors: %v", importResp.Diagnostics)
+	}
+	readResp := &resource.ReadResponse{State: importResp.State}
+	r.Read(ctx, resource.ReadRequest{State: importResp.State}, readResp)
+	if readResp.Diagnostics.HasError() {
+		t.Fatalf("unexpected errors: %v", readResp.Diagnostics)
+	}
+	var model resourceFileKeyModel
+	readResp.Diagnostics.Append(readResp.State.Get(ctx, &model)...)
+	if model.Value.ValueString() != "old" || !model.Document.IsNull() {
+		t.Errorf("expected the imported value to be read, got %+v", model)
+	}
+
+	deleteResp := &resource.DeleteResponse{State: readResp.State}
+	r.Delete(ctx, resource.DeleteRequest{State: readResp.State}, deleteResp)
+	if deleteResp.Diagnostics.HasError() {
+		t.Fatalf("unexpected errors: %v", deleteResp.Diagnostics)
+	}
+	if err := testCheckFileKey(filename, []string{"db"}, "")(nil); err != nil {
+		t.Errorf("expected the map left empty to be removed: %s", err)
+	}
+}
+
+func TestParseFileKeyID(t *testing.T) {
+	model := resourceFileKeyModel{
+		Filename: types.StringValue("C:/secrets/app.enc.yaml"),
+		Document: types.Int64Value(2),
+		Path:     types.ListValueMust(types.StringType, []attr.Value{types.StringValue("example.com"), types.StringValue(`a\b:1:c`)}),
+	}
+	id := model.id()
+	if id != `C:/secrets/app.enc.yaml:2:example\.com.a\\b:1:c` {
+		t.Errorf("unexpected ID %q", id)
+	}
+	filename, document, keys, err := parseFileKeyID(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if filename != "C:/secrets/app.enc.yaml" || document != 2 || strings.Join(keys, "|") != `example.com|a\b:1:c` {
+		t.Errorf("expected the ID to be parsed back, got %q, %d and %q", filename, document, keys)
+	}
+	if _, _, _, err := parseFileKeyID("secrets.enc.yaml:db.password"); err == nil {
+		t.Error("expected an ID without document to be rejected")
+	}
+}
+
+func TestTreeUnset(t *testing.T) {
+	list := mozillasops.TreeBranch{{Key: "list", Value: []interface{}{"a", "b", "c"}}}
+	unset, found := treeUnset(list, []string{"list", "0"})
+	if value, _ := treeGet(unset, []string{"list"}); !found || !reflect.DeepEqual(value, []interface{}{nil, "b", "c"}) {
+		t.Errorf("expected the element to be set to null, got %v", value)
+	}
+	unset, found = treeUnset(unset, []string{"list", "2"})
+	if value, _ := treeGet(unset, []string{"list"}); !found || !reflect.DeepEqual(value, []interface{}{nil, "b"}) {
+		t.Errorf("expected the last element to be removed, got %v", value)
+	}
+}
+
+func TestTreePrune(t *testing.T) {
+	branch := mozillasops.TreeBranch{
+		{Key: "a", Value: mozillasops.TreeBranch{{Key: "b", Value: mozillasops.TreeBranch{}}}},
+		{Key: "list", Value: []interface{}{mozillasops.TreeBranch{}, mozillasops.TreeBranch{}}},
+	}
+	pruned := treePrune(branch, []string{"a", "b", "c"})
+	if _, ok := treeGet(pruned, []string{"a"}); ok {
+		t.Errorf("expected the empty maps to be removed, got %v", pruned)
+	}
+	pruned = treePrune(pruned, []string{"list", "0", "c"})
+	if value, _ := treeGet(pruned, []string{"list"}); len(value.([]interface{})) != 2 {
+		t.Errorf("expected the first element to be kept, got %v", value)
+	}
+	pruned = treePrune(pruned, []string{"list", "1", "c"})
+	if value, _ := treeGet(pruned, []string{"list"}); len(value.([]interface{})) != 1 {
+		t.Errorf("expected the last element to be removed, got %v", value)
+	}
+}
@@ -0,0 +1,350 @@
+package sops
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// resourceFileModelV0 is the state written by the helper/schema
+// implementation, where the keys were maps of strings
+type resourceFileModelV0 struct {
+	ID                  types.String      `tfsdk:"id"`
+	Filename            types.String      `tfsdk:"filename"`
+	EncryptionType      types.String      `tfsdk:"encryption_type"`
+	Content             types.String      `tfsdk:"content"`
+	Kms                 map[string]string `tfsdk:"kms"`
+	GcpKms              map[string]string `tfsdk:"gcpkms"`
+	Age                 map[string]string `tfsdk:"age"`
+	FilePermission      types.String      `tfsdk:"file_permission"`
+	DirectoryPermission types.String      `tfsdk:"directory_permission"`
+	EncryptedRegex      types.String      `tfsdk:"encrypted_regex"`
+}
+
+// resourceFileModelV1 is the state of the first framework implementation,
+// where the keys were objects of strings
+type resourceFileModelV1 struct {
+	ID             types.String `tfsdk:"id"`
+	Filename       types.String `tfsdk:"filename"`
+	EncryptionType types.String `tfsdk:"encryption_type"`
+	Content        types.String `tfsdk:"content"`
+	Kms            *struct {
+		ARN     types.String `tfsdk:"arn"`
+		Profile types.String `tfsdk:"profile"`
+	} `tfsdk:"kms"`
+	GcpKms *struct {
+		IDs types.String `tfsdk:"ids"`
+	} `tfsdk:"gcpkms"`
+	Age *struct {
+		Key types.String `tfsdk:"key"`
+	} `tfsdk:"age"`
+	FilePermission      types.String `tfsdk:"file_permission"`
+	DirectoryPermission types.String `tfsdk:"directory_permission"`
+	EncryptedRegex      types.String `tfsdk:"encrypted_regex"`
+}
+
+// resourceFileModelV2 is the state before the permissions defaulted to 0600
+// and 0700. Its keys have the same attributes as the current ones.
+type resourceFileModelV2 struct {
+	ID             types.String  `tfsdk:"id"`
+	Filename       types.String  `tfsdk:"filename"`
+	EncryptionType types.String  `tfsdk:"encryption_type"`
+	Content        types.String  `tfsdk:"content"`
+	ContentObject  types.Dynamic `tfsdk:"content_object"`
+	Kms            *struct {
+		ARN     types.String `tfsdk:"arn"`
+		Profile types.String `tfsdk:"profile"`
+		Role    types.String `tfsdk:"role"`
+		Context types.Map    `tfsdk:"context"`
+	} `tfsdk:"kms"`
+	GcpKms *struct {
+		IDs         types.String `tfsdk:"ids"`
+		ResourceIDs types.List   `tfsdk:"resource_ids"`
+	} `tfsdk:"gcpkms"`
+	Age *struct {
+		Key            types.String `tfsdk:"key"`
+		Recipients     types.List   `tfsdk:"recipients"`
+		RecipientsFile types.String `tfsdk:"recipients_file"`
+		IdentityFile   types.String `tfsdk:"identity_file"`
+	} `tfsdk:"age"`
+	Pgp *struct {
+		Fingerprints types.List `tfsdk:"fingerprints"`
+	} `tfsdk:"pgp"`
+	Azkv *struct {
+		URLs types.List `tfsdk:"urls"`
+	} `tfsdk:"azkv"`
+	Vault *struct {
+		URIs types.List `tfsdk:"uris"`
+	} `tfsdk:"vault"`
+	FilePermission      types.String `tfsdk:"file_permission"`
+	DirectoryPermission types.String `tfsdk:"directory_permission"`
+	EncryptedRegex      types.String `tfsdk:"encrypted_regex"`
+	Deterministic       types.Bool   `tfsdk:"deterministic"`
+}
+
+func (r *resourceFile) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema:   resourceFileSchemaV0(),
+			StateUpgrader: upgradeResourceFileStateV0,
+		},
+		1: {
+			PriorSchema:   resourceFileSchemaV1(),
+			StateUpgrader: upgradeResourceFileStateV1,
+		},
+		2: {
+			PriorSchema:   resourceFileSchemaV2(),
+			StateUpgrader: upgradeResourceFileStateV2,
+		},
+	}
+}
+
+func resourceFileSchemaV0() *schema.Schema {
+	return &schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id":                   schema.StringAttribute{Computed: true},
+			"filename":             schema.StringAttribute{Required: true},
+			"encryption_type":      schema.StringAttribute{Required: true},
+			"content":              schema.StringAttribute{Optional: true},
+			"kms":                  schema.MapAttribute{ElementType: types.StringType, Optional: true},
+			"gcpkms":               schema.MapAttribute{ElementType: types.StringType, Optional: true},
+			"age":                  schema.MapAttribute{ElementType: types.StringType, Optional: true},
+			"file_permission":      schema.StringAttribute{Optional: true},
+			"directory_permission": schema.StringAttribute{Optional: true},
+			"encrypted_regex":      schema.StringAttribute{Optional: true},
+		},
+	}
+}
+
+func resourceFileSchemaV1() *schema.Schema {
+	return &schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id":              schema.StringAttribute{Computed: true},
+			"filename":        schema.StringAttribute{Required: true},
+			"encryption_type": schema.StringAttribute{Required: true},
+			"content":         schema.StringAttribute{Optional: true},
+			"kms": schema.SingleNestedAttribute{
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"arn":     schema.StringAttribute{Optional: true},
+					"profile": schema.StringAttribute{Optional: true},
+				},
+			},
+			"gcpkms": schema.SingleNestedAttribute{
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"ids": schema.StringAttribute{Optional: true},
+				},
+			},
+			"age": schema.SingleNestedAttribute{
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"key": schema.StringAttribute{Optional: true},
+				},
+			},
+			"file_permission":      schema.StringAttribute{Optional: true, Computed: true},
+			"directory_permission": schema.StringAttribute{Optional: true, Computed: true},
+			"encrypted_regex":      schema.StringAttribute{Optional: true},
+		},
+	}
+}
+
+// resourceFileSchemaV2 is the schema of version 2. States written before
+// content_object, pgp, azkv, vault and deterministic were added read them as
+// null.
+func resourceFileSchemaV2() *schema.Schema {
+	stringList := schema.ListAttribute{ElementType: types.StringType, Optional: true}
+	return &schema.Schema{
+		Version: 2,
+		Attributes: map[string]schema.Attribute{
+			"id":              schema.StringAttribute{Computed: true},
+			"filename":        schema.StringAttribute{Required: true},
+			"encryption_type": schema.StringAttribute{Optional: true},
+			"content":         schema.StringAttribute{Optional: true},
+			"content_object":  schema.DynamicAttribute{Optional: true, Sensitive: true},
+			"kms": schema.SingleNestedAttribute{
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"arn":     schema.StringAttribute{Optional: true},
+					"profile": schema.StringAttribute{Optional: true},
+					"role":    schema.StringAttribute{Optional: true},
+					"context": schema.MapAttribute{ElementType: types.StringType, Optional: true},
+				},
+			},
+			"gcpkms": schema.SingleNestedAttribute{
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"ids":          schema.StringAttribute{Optional: true},
+					"resource_ids": stringList,
+				},
+			},
+			"age": schema.SingleNestedAttribute{
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"key":             schema.StringAttribute{Optional: true},
+					"recipients":      stringList,
+					"recipients_file": schema.StringAttribute{Optional: true},
+					"identity_file":   schema.StringAttribute{Optional: true},
+				},
+			},
+			"pgp": schema.SingleNestedAttribute{
+				Optional:   true,
+				Attributes: map[string]schema.Attribute{"fingerprints": stringList},
+			},
+			"azkv": schema.SingleNestedAttribute{
+				Optional:   true,
+				Attributes: map[string]schema.Attribute{"urls": stringList},
+			},
+			"vault": schema.SingleNestedAttribute{
+				Optional:   true,
+				Attributes: map[string]schema.Attribute{"uris": stringList},
+			},
+			"file_permission":      schema.StringAttribute{Optional: true, Computed: true},
+			"directory_permission": schema.StringAttribute{Optional: true, Computed: true},
+			"encrypted_regex":      schema.StringAttribute{Optional: true},
+			"deterministic":        schema.BoolAttribute{Optional: true},
+		},
+	}
+}
+
+// upgradeResourceFileStateV0 moves the keys from maps of strings to nested
+// attributes. The file on disk is left untouched.
+func upgradeResourceFileStateV0(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var prior resourceFileModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	upgraded := resourceFileModel{
+		ID:                  prior.ID,
+		Filename:            prior.Filename,
+		EncryptionType:      prior.EncryptionType,
+		Content:             prior.Content,
+		ContentObject:       types.DynamicNull(),
+		Recipients:          types.ListNull(types.StringType),
+		FilePermission:      legacyPermission(prior.FilePermission),
+		DirectoryPermission: legacyPermission(prior.DirectoryPermission),
+		EncryptedRegex:      prior.EncryptedRegex,
+	}
+	if prior.Kms != nil {
+		upgraded.Kms = newResourceFileKms(optionalString(prior.Kms, "arn"), optionalString(prior.Kms, "profile"))
+	}
+	if prior.GcpKms != nil {
+		upgraded.GcpKms = newResourceFileGcpKms(optionalString(prior.GcpKms, "ids"))
+	}
+	if prior.Age != nil {
+		upgraded.Age = newResourceFileAge(optionalString(prior.Age, "key"))
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &upgraded)...)
+}
+
+// upgradeResourceFileStateV1 adds the typed key attributes, keeping the
+// comma separated values in the deprecated attributes they were set in
+func upgradeResourceFileStateV1(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var prior resourceFileModelV1
+	resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	upgraded := resourceFileModel{
+		ID:                  prior.ID,
+		Filename:            prior.Filename,
+		EncryptionType:      prior.EncryptionType,
+		Content:             prior.Content,
+		ContentObject:       types.DynamicNull(),
+		Recipients:          types.ListNull(types.StringType),
+		FilePermission:      legacyPermission(prior.FilePermission),
+		DirectoryPermission: legacyPermission(prior.DirectoryPermission),
+		EncryptedRegex:      prior.EncryptedRegex,
+	}
+	if prior.Kms != nil {
+		upgraded.Kms = newResourceFileKms(prior.Kms.ARN, prior.Kms.Profile)
+	}
+	if prior.GcpKms != nil {
+		upgraded.GcpKms = newResourceFileGcpKms(prior.GcpKms.IDs)
+	}
+	if prior.Age != nil {
+		upgraded.Age = newResourceFileAge(prior.Age.Key)
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &upgraded)...)
+}
+
+// upgradeResourceFileStateV2 records the permissions files were created with
+// before the defaults became 0600 and 0700, so they are kept until the files
+// are recreated
+func upgradeResourceFileStateV2(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var prior resourceFileModelV2
+	resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The key objects convert as is, and stop compiling if they change
+	// without a new schema version
+	upgraded := resourceFileModel{
+		ID:                  prior.ID,
+		Filename:            prior.Filename,
+		EncryptionType:      prior.EncryptionType,
+		Content:             prior.Content,
+		ContentObject:       prior.ContentObject,
+		Kms:                 (*resourceFileKms)(prior.Kms),
+		GcpKms:              (*resourceFileGcpKms)(prior.GcpKms),
+		Age:                 (*resourceFileAge)(prior.Age),
+		Pgp:                 (*resourceFilePgp)(prior.Pgp),
+		Azkv:                (*resourceFileAzkv)(prior.Azkv),
+		Vault:               (*resourceFileVault)(prior.Vault),
+		Recipients:          types.ListNull(types.StringType),
+		FilePermission:      legacyPermission(prior.FilePermission),
+		DirectoryPermission: legacyPermission(prior.DirectoryPermission),
+		EncryptedRegex:      prior.EncryptedRegex,
+		Deterministic:       prior.Deterministic,
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &upgraded)...)
+}
+
+// legacyPermission returns the permission of files created before version 3
+// of the schema, where it defaulted to 0777
+func legacyPermission(permission types.String) types.String {
+	if permission.IsNull() || permission.IsUnknown() {
+		return types.StringValue("0777")
+	}
+	return permission
+}
+
+func newResourceFileKms(arn, profile types.String) *resourceFileKms {
+	return &resourceFileKms{
+		ARN:     arn,
+		Profile: profile,
+		Role:    types.StringNull(),
+		Context: types.MapNull(types.StringType),
+	}
+}
+
+func newResourceFileGcpKms(ids types.String) *resourceFileGcpKms {
+	return &resourceFileGcpKms{
+		IDs:         ids,
+		ResourceIDs: types.ListNull(types.StringType),
+	}
+}
+
+func newResourceFileAge(key types.String) *resourceFileAge {
+	return &resourceFileAge{
+		Key:            key,
+		Recipients:     types.ListNull(types.StringType),
+		RecipientsFile: types.StringNull(),
+		IdentityFile:   types.StringNull(),
+	}
+}
+
+// optionalString returns m[k], or a null string when k is not set
+func optionalString(m map[string]string, k string) types.String {
+	if v, ok := m[k]; ok {
+		return types.StringValue(v)
+	}
+	return types.StringNull()
+}
@@ -0,0 +1,261 @@
+package sops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/dynamicplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// resourceFileKeyAttributes are the attributes the key group is built from
+var resourceFileKeyAttributes = []string{"encryption_type", "kms", "gcpkms", "age", "pgp", "azkv", "vault"}
+
+// resourceFileEncryptedAttributes are the attributes the encrypted file is
+// built from. Updating any of them in place rewrites the file.
+var resourceFileEncryptedAttributes = append([]string{"content", "content_object", "encrypted_regex"}, resourceFileKeyAttributes...)
+
+// ModifyPlan reports the errors Create would run into, so they surface during
+// plan instead of apply
+func (r *resourceFile) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var model resourceFileModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	contentAttribute := "content"
+	if !model.ContentObject.IsNull() {
+		contentAttribute = "content_object"
+		if !model.Content.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("content_object"), "Conflicting content", "Only one of content and content_object can be set.")
+		}
+	}
+	if planKnown(req.Plan, "filename", contentAttribute) {
+		if err := validatePlainModel(ctx, model); err != nil {
+			var encrypted *fileAlreadyEncryptedError
+			if errors.As(err, &encrypted) {
+				resp.Diagnostics.AddAttributeError(path.Root(contentAttribute), "Content is already encrypted", encrypted.UserError())
+			} else {
+				resp.Diagnostics.AddAttributeError(path.Root(contentAttribute), "Invalid content", err.Error())
+			}
+		}
+	}
+
+	config := r.config
+	if config == nil {
+		config = &EncryptConfig{}
+	}
+	if config.Unknown || !planKnown(req.Plan, resourceFileKeyAttributes...) {
+		return
+	}
+	keyConf, diags := model.keyConf(ctx)
+	resp.Diagnostics.Append(diags...)
+	if diags.HasError() {
+		return
+	}
+	encType := model.EncryptionType.ValueString()
+	if _, err := KeyGroups(ctx, keyConf, encType, config); err != nil {
+		if attr := encryptionTypeAttribute(encType); attr != "" {
+			resp.Diagnostics.AddAttributeError(path.Root(attr), "Invalid keys", err.Error())
+		} else {
+			resp.Diagnostics.AddError("Invalid keys", err.Error())
+		}
+	}
+}
+
+// validatePlainModel parses content, or converts content_object, with the
+// store selected by filename and ensures it doesn't hold sops metadata already
+func validatePlainModel(ctx context.Context, model resourceFileModel) error {
+	branches, err := model.branches(ctx)
+	if err != nil {
+		return err
+	}
+	if !model.ContentObject.IsNull() {
+		// The stores can only fail to emit objects, such as nested values in
+		// dotenv files, since parsed content fits them already
+		if _, err := GetOutputStore(model.Filename.ValueString()).EmitPlainFile(branches); err != nil {
+			return err
+		}
+	}
+	for _, branch := range branches {
+		if err := ensureNoMetadata(branch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encryptionTypeAttribute returns the attribute holding the keys required by
+// encType, or encryption_type itself when it requires several
+func encryptionTypeAttribute(encType string) string {
+	switch encType {
+	case "kms", "gcpkms", "age":
+		return encType
+	case "":
+		return ""
+	}
+	return "encryption_type"
+}
+
+// attributesChanged reports whether any of the top-level attributes differ
+// between the prior state and the plan
+func attributesChanged(state, plan tftypes.Value, attributes ...string) bool {
+	for _, attribute := range attributes {
+		step := tftypes.AttributeName(attribute)
+		prior, err := state.ApplyTerraform5AttributePathStep(step)
+		if err != nil {
+			return true
+		}
+		planned, err := plan.ApplyTerraform5AttributePathStep(step)
+		if err != nil {
+			return true
+		}
+		if !prior.(tftypes.Value).Equal(planned.(tftypes.Value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// planRewritesFile reports whether applying plan over state writes the file
+// again, which only happens for in-place updates of deterministic files
+func planRewritesFile(ctx context.Context, state tfsdk.State, plan tfsdk.Plan) bool {
+	return deterministicPlan(ctx, plan) && attributesChanged(state.Raw, plan.Raw, resourceFileEncryptedAttributes...)
+}
+
+// planKnown reports whether the top-level attributes are fully known
+func planKnown(plan tfsdk.Plan, attributes ...string) bool {
+	for _, attribute := range attributes {
+		v, err := plan.Raw.ApplyTerraform5AttributePathStep(tftypes.AttributeName(attribute))
+		if err != nil {
+			return false
+		}
+		if value, ok := v.(tftypes.Value); !ok || !value.IsFullyKnown() {
+			return false
+		}
+	}
+	return true
+}
+
+// deterministicPlan reports whether deterministic is set in plan. Arguments
+// are then updated in place instead of recreating the file, since the file
+// must stay on disk to be compared with the new content.
+func deterministicPlan(ctx context.Context, plan tfsdk.Plan) bool {
+	var deterministic types.Bool
+	plan.GetAttribute(ctx, path.Root("deterministic"), &deterministic)
+	return deterministic.ValueBool()
+}
+
+func stringRequiresReplaceUnlessDeterministic() planmodifier.String {
+	return stringplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+			resp.RequiresReplace = !deterministicPlan(ctx, req.Plan)
+		},
+		"Changing the value recreates the file, unless deterministic is set.",
+		"Changing the value recreates the file, unless `deterministic` is set.",
+	)
+}
+
+func objectRequiresReplaceUnlessDeterministic() planmodifier.Object {
+	return objectplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.ObjectRequest, resp *objectplanmodifier.RequiresReplaceIfFuncResponse) {
+			resp.RequiresReplace = !deterministicPlan(ctx, req.Plan)
+		},
+		"Changing the value recreates the file, unless deterministic is set.",
+		"Changing the value recreates the file, unless `deterministic` is set.",
+	)
+}
+
+func dynamicRequiresReplaceUnlessDeterministic() planmodifier.Dynamic {
+	return dynamicplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.DynamicRequest, resp *dynamicplanmodifier.RequiresReplaceIfFuncResponse) {
+			resp.RequiresReplace = !deterministicPlan(ctx, req.Plan)
+		},
+		"Changing the value recreates the file, unless deterministic is set.",
+		"Changing the value recreates the file, unless `deterministic` is set.",
+	)
+}
+
+// permissionDefault sets the permissions of new files to value when they
+// aren't configured, and keeps the permissions of existing files, which were
+// created when the default was 0777, until they are recreated
+type permissionDefault struct {
+	value string
+}
+
+var _ planmodifier.String = permissionDefault{}
+
+func (m permissionDefault) Description(ctx context.Context) string {
+	return fmt.Sprintf("Defaults to %s for new files.", m.value)
+}
+
+func (m permissionDefault) MarkdownDescription(ctx context.Context) string {
+	return fmt.Sprintf("Defaults to `%s` for new files.", m.value)
+}
+
+func (m permissionDefault) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if !req.ConfigValue.IsNull() {
+		return
+	}
+	if !req.StateValue.IsNull() {
+		resp.PlanValue = req.StateValue
+		return
+	}
+	resp.PlanValue = types.StringValue(m.value)
+}
+
+// fileAttributeUseState keeps the prior value of an attribute describing the
+// encrypted file on in-place updates, like UseStateForUnknown, unless the
+// update writes the file again
+type fileAttributeUseState struct{}
+
+var (
+	_ planmodifier.String = fileAttributeUseState{}
+	_ planmodifier.List   = fileAttributeUseState{}
+)
+
+func (m fileAttributeUseState) Description(ctx context.Context) string {
+	return "Keeps the prior value unless the file is encrypted again."
+}
+
+func (m fileAttributeUseState) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+// useState reports whether the prior value stays valid for the planned update
+func (m fileAttributeUseState) useState(ctx context.Context, state tfsdk.State, plan tfsdk.Plan) bool {
+	if state.Raw.IsNull() || plan.Raw.IsNull() {
+		return false
+	}
+	return !planRewritesFile(ctx, state, plan)
+}
+
+func (m fileAttributeUseState) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if req.StateValue.IsNull() || !req.PlanValue.IsUnknown() {
+		return
+	}
+	if m.useState(ctx, req.State, req.Plan) {
+		resp.PlanValue = req.StateValue
+	}
+}
+
+func (m fileAttributeUseState) PlanModifyList(ctx context.Context, req planmodifier.ListRequest, resp *planmodifier.ListResponse) {
+	if req.StateValue.IsNull() || !req.PlanValue.IsUnknown() {
+		return
+	}
+	if m.useState(ctx, req.State, req.Plan) {
+		resp.PlanValue = req.StateValue
+	}
+}
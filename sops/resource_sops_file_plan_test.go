@@ -0,0 +1,198 @@
+package sops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func testResourceFileValue(t *testing.T, attrs map[string]tftypes.Value) (tfsdk.Plan, tfsdk.State) {
+	t.Helper()
+	ctx := context.Background()
+	var schemaResp resource.SchemaResponse
+	(&resourceFile{}).Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	objType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := make(map[string]tftypes.Value, len(objType.AttributeTypes))
+	for name, typ := range objType.AttributeTypes {
+		if v, ok := attrs[name]; ok {
+			values[name] = v
+		} else {
+			values[name] = tftypes.NewValue(typ, nil)
+		}
+	}
+	raw := tftypes.NewValue(objType, values)
+	return tfsdk.Plan{Schema: schemaResp.Schema, Raw: raw}, tfsdk.State{Schema: schemaResp.Schema, Raw: raw}
+}
+
+func testResourceFileModifyPlan(t *testing.T, config *EncryptConfig, attrs map[string]tftypes.Value) diag.Diagnostics {
+	t.Helper()
+	plan, _ := testResourceFileValue(t, attrs)
+	r := &resourceFile{config: config}
+	resp := &resource.ModifyPlanResponse{Plan: plan}
+	r.ModifyPlan(context.Background(), resource.ModifyPlanRequest{Plan: plan}, resp)
+	return resp.Diagnostics
+}
+
+func testAgeAttribute(recipient string) tftypes.Value {
+	list := tftypes.List{ElementType: tftypes.String}
+	return tftypes.NewValue(tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+		"key":             tftypes.String,
+		"recipients":      list,
+		"recipients_file": tftypes.String,
+		"identity_file":   tftypes.String,
+	}}, map[string]tftypes.Value{
+		"key":             tftypes.NewValue(tftypes.String, nil),
+		"recipients":      tftypes.NewValue(list, []tftypes.Value{tftypes.NewValue(tftypes.String, recipient)}),
+		"recipients_file": tftypes.NewValue(tftypes.String, nil),
+		"identity_file":   tftypes.NewValue(tftypes.String, nil),
+	})
+}
+
+func TestResourceFileModifyPlan_valid(t *testing.T) {
+	diags := testResourceFileModifyPlan(t, &EncryptConfig{}, map[string]tftypes.Value{
+		"filename": tftypes.NewValue(tftypes.String, "secret.enc.yaml"),
+		"content":  tftypes.NewValue(tftypes.String, "hello: world\n"),
+		"age":      testAgeAttribute(testAgeRecipient),
+	})
+	if diags.HasError() {
+		t.Errorf("unexpected errors: %v", diags)
+	}
+}
+
+func TestResourceFileModifyPlan_alreadyEncrypted(t *testing.T) {
+	diags := testResourceFileModifyPlan(t, &EncryptConfig{}, map[string]tftypes.Value{
+		"filename": tftypes.NewValue(tftypes.String, "secret.enc.yaml"),
+		"content":  tftypes.NewValue(tftypes.String, "hello: world\nsops:\n  version: 3.7.3\n"),
+		"age":      testAgeAttribute(testAgeRecipient),
+	})
+	if diags.ErrorsCount() != 1 || !diags.Contains(diag.NewAttributeErrorDiagnostic(path.Root("content"), "Content is already encrypted", (&fileAlreadyEncryptedError{}).UserError())) {
+		t.Errorf("expected an error on content, got %v", diags)
+	}
+}
+
+func TestResourceFileModifyPlan_invalidContent(t *testing.T) {
+	diags := testResourceFileModifyPlan(t, &EncryptConfig{}, map[string]tftypes.Value{
+		"filename": tftypes.NewValue(tftypes.String, "secret.enc.json"),
+		"content":  tftypes.NewValue(tftypes.String, "{"),
+		"age":      testAgeAttribute(testAgeRecipient),
+	})
+	if diags.ErrorsCount() != 1 || diags.Errors()[0].Summary() != "Invalid content" {
+		t.Errorf("expected an error on content, got %v", diags)
+	}
+}
+
+func TestResourceFileModifyPlan_missingKeys(t *testing.T) {
+	diags := testResourceFileModifyPlan(t, &EncryptConfig{}, map[string]tftypes.Value{
+		"filename":        tftypes.NewValue(tftypes.String, "secret.enc.yaml"),
+		"content":         tftypes.NewValue(tftypes.String, "hello: world\n"),
+		"encryption_type": tftypes.NewValue(tftypes.String, "age"),
+	})
+	if diags.ErrorsCount() != 1 {
+		t.Fatalf("expected an error, got %v", diags)
+	}
+	if withPath, ok := diags.Errors()[0].(diag.DiagnosticWithPath); !ok || !withPath.Path().Equal(path.Root("age")) {
+		t.Errorf("expected the error on age, got %v", diags)
+	}
+
+	diags = testResourceFileModifyPlan(t, &EncryptConfig{}, map[string]tftypes.Value{
+		"filename": tftypes.NewValue(tftypes.String, "secret.enc.yaml"),
+		"content":  tftypes.NewValue(tftypes.String, "hello: world\n"),
+	})
+	if !diags.HasError() {
+		t.Error("expected an error without any key")
+	}
+}
+
+func TestResourceFileModifyPlan_unknown(t *testing.T) {
+	diags := testResourceFileModifyPlan(t, &EncryptConfig{Unknown: true}, map[string]tftypes.Value{
+		"filename": tftypes.NewValue(tftypes.String, "secret.enc.yaml"),
+		"content":  tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+	})
+	if diags.HasError() {
+		t.Errorf("expected unknown values to be skipped, got %v", diags)
+	}
+}
+
+func testContentObjectAttribute() tftypes.Value {
+	nested := tftypes.Object{AttributeTypes: map[string]tftypes.Type{"password": tftypes.String}}
+	return tftypes.NewValue(tftypes.Object{AttributeTypes: map[string]tftypes.Type{"db": nested}}, map[string]tftypes.Value{
+		"db": tftypes.NewValue(nested, map[string]tftypes.Value{"password": tftypes.NewValue(tftypes.String, "s3cr3t")}),
+	})
+}
+
+func TestResourceFileModifyPlan_contentObject(t *testing.T) {
+	diags := testResourceFileModifyPlan(t, &EncryptConfig{}, map[string]tftypes.Value{
+		"filename":       tftypes.NewValue(tftypes.String, "secret.enc.yaml"),
+		"content_object": testContentObjectAttribute(),
+		"age":            testAgeAttribute(testAgeRecipient),
+	})
+	if diags.HasError() {
+		t.Errorf("unexpected errors: %v", diags)
+	}
+
+	diags = testResourceFileModifyPlan(t, &EncryptConfig{}, map[string]tftypes.Value{
+		"filename":       tftypes.NewValue(tftypes.String, "secret.enc.env"),
+		"content_object": testContentObjectAttribute(),
+		"age":            testAgeAttribute(testAgeRecipient),
+	})
+	if diags.ErrorsCount() != 1 || !diags.Errors()[0].(diag.DiagnosticWithPath).Path().Equal(path.Root("content_object")) {
+		t.Errorf("expected nested values to be rejected in dotenv files, got %v", diags)
+	}
+
+	diags = testResourceFileModifyPlan(t, &EncryptConfig{}, map[string]tftypes.Value{
+		"filename":       tftypes.NewValue(tftypes.String, "secret.enc.yaml"),
+		"content":        tftypes.NewValue(tftypes.String, "hello: world\n"),
+		"content_object": testContentObjectAttribute(),
+		"age":            testAgeAttribute(testAgeRecipient),
+	})
+	if diags.ErrorsCount() != 1 || diags.Errors()[0].Summary() != "Conflicting content" {
+		t.Errorf("expected content and content_object to conflict, got %v", diags)
+	}
+}
+
+func TestFileAttributeUseState(t *testing.T) {
+	ctx := context.Background()
+	_, state := testResourceFileValue(t, map[string]tftypes.Value{
+		"filename":      tftypes.NewValue(tftypes.String, "secret.enc.yaml"),
+		"content":       tftypes.NewValue(tftypes.String, "hello: world\n"),
+		"deterministic": tftypes.NewValue(tftypes.Bool, true),
+		"id":            tftypes.NewValue(tftypes.String, "abc"),
+	})
+	for _, tc := range []struct {
+		name          string
+		content       string
+		deterministic bool
+		keep          bool
+	}{
+		{"unchanged content", "hello: world\n", true, true},
+		{"changed content", "hello: there\n", true, false},
+		{"replaced file", "hello: there\n", false, true},
+	} {
+		plan, _ := testResourceFileValue(t, map[string]tftypes.Value{
+			"filename":      tftypes.NewValue(tftypes.String, "secret.enc.yaml"),
+			"content":       tftypes.NewValue(tftypes.String, tc.content),
+			"deterministic": tftypes.NewValue(tftypes.Bool, tc.deterministic),
+			"id":            tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+		})
+		req := planmodifier.StringRequest{
+			Path:       path.Root("id"),
+			State:      state,
+			Plan:       plan,
+			StateValue: types.StringValue("abc"),
+			PlanValue:  types.StringUnknown(),
+		}
+		resp := &planmodifier.StringResponse{PlanValue: req.PlanValue}
+		fileAttributeUseState{}.PlanModifyString(ctx, req, resp)
+		if kept := !resp.PlanValue.IsUnknown(); kept != tc.keep {
+			t.Errorf("%s: expected the prior id to be kept: %t, got %s", tc.name, tc.keep, resp.PlanValue)
+		}
+	}
+}
@@ -0,0 +1,501 @@
+package sops
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	sdkresource "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const testAgeRecipient = "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
+
+const testPgpFingerprint = "3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A"
+
+const configTestResourceSopsFile_age = `
+resource "sops_file" "test_age" {
+  filename        = "%s"
+  encryption_type = "age"
+  content         = jsonencode({ hello = "world" })
+  age = {
+    recipients = ["%s"]
+  }
+}`
+
+func TestResourceSopsFile_age(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.enc.json")
+	sdkresource.UnitTest(t, sdkresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []sdkresource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_age, filename, testAgeRecipient),
+				Check: sdkresource.ComposeTestCheckFunc(
+					sdkresource.TestCheckResourceAttr("sops_file.test_age", "file_permission", "0600"),
+					testCheckFileEncryptedFor(filename, testAgeRecipient),
+				),
+			},
+		},
+	})
+}
+
+const configTestResourceSopsFile_recipients = `
+resource "sops_file" "test_recipients" {
+  filename = "%s"
+  content  = jsonencode({ hello = "world" })
+  age = {
+    recipients = ["%s"]
+  }
+  pgp = {
+    fingerprints = ["%s"]
+  }
+}`
+
+func TestResourceSopsFile_recipients(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.enc.json")
+	sdkresource.UnitTest(t, sdkresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []sdkresource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_recipients, filename, testAgeRecipient, testPgpFingerprint),
+				Check: sdkresource.ComposeTestCheckFunc(
+					sdkresource.TestCheckNoResourceAttr("sops_file.test_recipients", "encryption_type"),
+					testCheckFileEncryptedFor(filename, testAgeRecipient),
+					testCheckFileEncryptedFor(filename, testPgpFingerprint),
+				),
+			},
+		},
+	})
+}
+
+const configTestResourceSopsFile_contentObject = `
+resource "sops_file" "test_content_object" {
+  filename = "%s"
+  content_object = {
+    db = { password = "s3cr3t", port = 5432 }
+  }
+  pgp = {
+    fingerprints = ["%s"]
+  }
+}`
+
+func TestResourceSopsFile_contentObject(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.enc.yaml")
+	sdkresource.UnitTest(t, sdkresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []sdkresource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_contentObject, filename, testPgpFingerprint),
+				Check: sdkresource.ComposeTestCheckFunc(
+					testCheckFileKey(filename, []string{"db", "password"}, "s3cr3t"),
+					testCheckFileKey(filename, []string{"db", "port"}, "5432"),
+				),
+			},
+		},
+	})
+}
+
+const configTestResourceSopsFile_multiDocument = `
+resource "sops_file" "test_multi_document" {
+  filename = "%s"
+  content  = "name: db\n---\nname: api\n"
+  pgp = {
+    fingerprints = ["%s"]
+  }
+}
+
+data "sops_file" "test_multi_document" {
+  source_file = sops_file.test_multi_document.filename
+}`
+
+func TestResourceSopsFile_multiDocument(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "manifests.enc.yaml")
+	sdkresource.UnitTest(t, sdkresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []sdkresource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_multiDocument, filename, testPgpFingerprint),
+				Check: sdkresource.ComposeTestCheckFunc(
+					sdkresource.TestCheckResourceAttr("data.sops_file.test_multi_document", "documents.#", "2"),
+					sdkresource.TestCheckResourceAttr("data.sops_file.test_multi_document", "documents.0.name", "db"),
+					sdkresource.TestCheckResourceAttr("data.sops_file.test_multi_document", "documents.1.name", "api"),
+				),
+			},
+		},
+	})
+}
+
+const configTestResourceSopsFile_deterministic = `
+resource "sops_file" "test_deterministic" {
+  filename      = "%s"
+  content       = "a: one\nb: %s\n"
+  deterministic = true
+  pgp = {
+    fingerprints = ["%s"]
+  }
+}`
+
+func TestResourceSopsFile_deterministic(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.enc.yaml")
+	sdkresource.UnitTest(t, sdkresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []sdkresource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_deterministic, filename, "two", testPgpFingerprint),
+				Check:  testCheckFileKey(filename, []string{"b"}, "two"),
+			},
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_deterministic, filename, "three", testPgpFingerprint),
+				Check: sdkresource.ComposeTestCheckFunc(
+					testCheckFileKey(filename, []string{"a"}, "one"),
+					testCheckFileKey(filename, []string{"b"}, "three"),
+				),
+			},
+		},
+	})
+}
+
+const configTestResourceSopsFile_destroy = `
+resource "sops_file" "test_destroy" {
+  filename        = "%s"
+  content         = "hello: world\n"
+  keep_on_destroy = %t
+  pgp = {
+    fingerprints = ["%s"]
+  }
+}`
+
+func TestResourceSopsFile_keepOnDestroy(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.enc.yaml")
+	sdkresource.UnitTest(t, sdkresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testCheckFileKey(filename, []string{"hello"}, "world"),
+		Steps: []sdkresource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_destroy, filename, true, testPgpFingerprint),
+			},
+		},
+	})
+}
+
+func TestResourceSopsFile_removeCreatedDirectories(t *testing.T) {
+	root := t.TempDir()
+	filename := filepath.Join(root, "secrets", "prod", "secret.enc.yaml")
+	sdkresource.UnitTest(t, sdkresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(s *terraform.State) error {
+			if _, err := os.Stat(filepath.Join(root, "secrets")); !os.IsNotExist(err) {
+				return fmt.Errorf("expected the created directories to be removed, got %v", err)
+			}
+			return nil
+		},
+		Steps: []sdkresource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_destroy, filename, false, testPgpFingerprint),
+				Check:  testCheckFileKey(filename, []string{"hello"}, "world"),
+			},
+		},
+	})
+}
+
+const configTestResourceSopsFile_followSymlinks = `
+resource "sops_file" "test_follow_symlinks" {
+  filename        = "%s"
+  content         = "hello: world\n"
+  follow_symlinks = true
+  shred           = true
+  pgp = {
+    fingerprints = ["%s"]
+  }
+}`
+
+func TestResourceSopsFile_followSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.enc.yaml")
+	link := filepath.Join(dir, "secret.enc.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symbolic links are not supported: %s", err)
+	}
+	sdkresource.UnitTest(t, sdkresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testCheckSymlinkTargetRemoved(link, target),
+		Steps: []sdkresource.TestStep{
+			{
+				Config: fmt.Sprintf(configTestResourceSopsFile_followSymlinks, link, testPgpFingerprint),
+				Check:  testCheckFileKey(target, []string{"hello"}, "world"),
+			},
+		},
+	})
+}
+
+// TestResourceFileDelete_followSymlinks destroys a file written through a
+// symbolic link without the terraform CLI
+func TestResourceFileDelete_followSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.enc.yaml")
+	link := filepath.Join(dir, "secret.enc.yaml")
+	if err := ioutil.WriteFile(target, []byte("hello: world\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symbolic links are not supported: %s", err)
+	}
+	_, state := testResourceFileValue(t, map[string]tftypes.Value{
+		"filename":        tftypes.NewValue(tftypes.String, link),
+		"follow_symlinks": tftypes.NewValue(tftypes.Bool, true),
+		"shred":           tftypes.NewValue(tftypes.Bool, true),
+	})
+	resp := &resource.DeleteResponse{}
+	(&resourceFile{}).Delete(context.Background(), resource.DeleteRequest{State: state}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected errors: %v", resp.Diagnostics)
+	}
+	if err := testCheckSymlinkTargetRemoved(link, target)(nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// testCheckSymlinkTargetRemoved checks that the file written through link
+// was removed, and that the link was left in place
+func testCheckSymlinkTargetRemoved(link, target string) sdkresource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if _, err := os.Stat(target); !os.IsNotExist(err) {
+			return fmt.Errorf("expected the target of the link to be removed, got %v", err)
+		}
+		if _, err := os.Lstat(link); err != nil {
+			return fmt.Errorf("expected the link to be left in place, got %v", err)
+		}
+		return nil
+	}
+}
+
+func testCheckFileEncryptedFor(filename, recipient string) sdkresource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(content), recipient) {
+			return fmt.Errorf("expected %s to be encrypted for %s", filename, recipient)
+		}
+		if strings.Contains(string(content), "world") {
+			return fmt.Errorf("expected %s not to contain the cleartext", filename)
+		}
+		return nil
+	}
+}
+
+func TestResourceSopsFile_upgradeStateV0(t *testing.T) {
+	ctx := context.Background()
+	r := &resourceFile{}
+	upgrader := r.UpgradeState(ctx)[0]
+
+	priorType := upgrader.PriorSchema.Type().TerraformType(ctx).(tftypes.Object)
+	stringMap := tftypes.Map{ElementType: tftypes.String}
+	prior := tftypes.NewValue(priorType, map[string]tftypes.Value{
+		"id":              tftypes.NewValue(tftypes.String, "0123"),
+		"filename":        tftypes.NewValue(tftypes.String, "secret.enc.json"),
+		"encryption_type": tftypes.NewValue(tftypes.String, "kms"),
+		"content":         tftypes.NewValue(tftypes.String, "{}"),
+		"kms": tftypes.NewValue(stringMap, map[string]tftypes.Value{
+			"arn": tftypes.NewValue(tftypes.String, "arn:aws:kms:us-east-1:123456789012:key/abc"),
+		}),
+		"gcpkms":               tftypes.NewValue(stringMap, nil),
+		"age":                  tftypes.NewValue(stringMap, nil),
+		"file_permission":      tftypes.NewValue(tftypes.String, "0777"),
+		"directory_permission": tftypes.NewValue(tftypes.String, "0777"),
+		"encrypted_regex":      tftypes.NewValue(tftypes.String, nil),
+	})
+
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	req := resource.UpgradeStateRequest{
+		State: &tfsdk.State{Schema: *upgrader.PriorSchema, Raw: prior},
+	}
+	resp := &resource.UpgradeStateResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema},
+	}
+	upgrader.StateUpgrader(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	var upgraded resourceFileModel
+	if diags := resp.State.Get(ctx, &upgraded); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if upgraded.ID.ValueString() != "0123" {
+		t.Errorf("expected the id to be kept, got %s", upgraded.ID)
+	}
+	if upgraded.Kms == nil || upgraded.Kms.ARN.ValueString() != "arn:aws:kms:us-east-1:123456789012:key/abc" {
+		t.Errorf("expected kms.arn to be moved, got %+v", upgraded.Kms)
+	}
+	if upgraded.Kms != nil && !upgraded.Kms.Profile.IsNull() {
+		t.Errorf("expected kms.profile to be null, got %s", upgraded.Kms.Profile)
+	}
+	if upgraded.Age != nil || upgraded.GcpKms != nil {
+		t.Errorf("expected age and gcpkms to stay null, got %+v and %+v", upgraded.Age, upgraded.GcpKms)
+	}
+}
+
+func TestResourceSopsFile_upgradeStateV1(t *testing.T) {
+	ctx := context.Background()
+	r := &resourceFile{}
+	upgrader := r.UpgradeState(ctx)[1]
+
+	priorType := upgrader.PriorSchema.Type().TerraformType(ctx).(tftypes.Object)
+	ageType := priorType.AttributeTypes["age"]
+	prior := tftypes.NewValue(priorType, map[string]tftypes.Value{
+		"id":              tftypes.NewValue(tftypes.String, "0123"),
+		"filename":        tftypes.NewValue(tftypes.String, "secret.enc.json"),
+		"encryption_type": tftypes.NewValue(tftypes.String, "age"),
+		"content":         tftypes.NewValue(tftypes.String, "{}"),
+		"kms":             tftypes.NewValue(priorType.AttributeTypes["kms"], nil),
+		"gcpkms":          tftypes.NewValue(priorType.AttributeTypes["gcpkms"], nil),
+		"age": tftypes.NewValue(ageType, map[string]tftypes.Value{
+			"key": tftypes.NewValue(tftypes.String, testAgeRecipient),
+		}),
+		"file_permission":      tftypes.NewValue(tftypes.String, "0777"),
+		"directory_permission": tftypes.NewValue(tftypes.String, "0777"),
+		"encrypted_regex":      tftypes.NewValue(tftypes.String, nil),
+	})
+
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	req := resource.UpgradeStateRequest{
+		State: &tfsdk.State{Schema: *upgrader.PriorSchema, Raw: prior},
+	}
+	resp := &resource.UpgradeStateResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema},
+	}
+	upgrader.StateUpgrader(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	var upgraded resourceFileModel
+	if diags := resp.State.Get(ctx, &upgraded); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if upgraded.Age == nil || upgraded.Age.Key.ValueString() != testAgeRecipient || !upgraded.Age.Recipients.IsNull() {
+		t.Errorf("expected age.key to be kept and recipients to be null, got %+v", upgraded.Age)
+	}
+	keyConf, diags := upgraded.keyConf(ctx)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if len(keyConf.Age) != 1 || keyConf.Age[0] != testAgeRecipient {
+		t.Errorf("expected the key to be used as recipient, got %v", keyConf.Age)
+	}
+}
+
+func TestResourceSopsFile_upgradeStateV2(t *testing.T) {
+	ctx := context.Background()
+	r := &resourceFile{}
+	upgrader := r.UpgradeState(ctx)[2]
+
+	priorType := upgrader.PriorSchema.Type().TerraformType(ctx).(tftypes.Object)
+	values := make(map[string]tftypes.Value, len(priorType.AttributeTypes))
+	for name, typ := range priorType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["id"] = tftypes.NewValue(tftypes.String, "0123")
+	values["filename"] = tftypes.NewValue(tftypes.String, "secret.enc.json")
+	values["directory_permission"] = tftypes.NewValue(tftypes.String, "0755")
+	ageType := priorType.AttributeTypes["age"].(tftypes.Object)
+	ageValues := make(map[string]tftypes.Value, len(ageType.AttributeTypes))
+	for name, typ := range ageType.AttributeTypes {
+		ageValues[name] = tftypes.NewValue(typ, nil)
+	}
+	ageValues["recipients"] = tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{tftypes.NewValue(tftypes.String, testAgeRecipient)})
+	values["age"] = tftypes.NewValue(ageType, ageValues)
+
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	req := resource.UpgradeStateRequest{
+		State: &tfsdk.State{Schema: *upgrader.PriorSchema, Raw: tftypes.NewValue(priorType, values)},
+	}
+	resp := &resource.UpgradeStateResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema},
+	}
+	upgrader.StateUpgrader(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	var upgraded resourceFileModel
+	if diags := resp.State.Get(ctx, &upgraded); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if upgraded.FilePermission.ValueString() != "0777" {
+		t.Errorf("expected the legacy file permission, got %s", upgraded.FilePermission)
+	}
+	if upgraded.DirectoryPermission.ValueString() != "0755" {
+		t.Errorf("expected the directory permission to be kept, got %s", upgraded.DirectoryPermission)
+	}
+	if upgraded.Age == nil || len(upgraded.Age.Recipients.Elements()) != 1 {
+		t.Errorf("expected the age recipients to be kept, got %v", upgraded.Age)
+	}
+}
+
+func TestPermissionDefault(t *testing.T) {
+	ctx := context.Background()
+	m := permissionDefault{value: "0600"}
+
+	resp := &planmodifier.StringResponse{PlanValue: types.StringUnknown()}
+	m.PlanModifyString(ctx, planmodifier.StringRequest{ConfigValue: types.StringNull(), StateValue: types.StringNull()}, resp)
+	if resp.PlanValue.ValueString() != "0600" {
+		t.Errorf("expected new files to default to 0600, got %s", resp.PlanValue)
+	}
+
+	resp = &planmodifier.StringResponse{PlanValue: types.StringUnknown()}
+	m.PlanModifyString(ctx, planmodifier.StringRequest{ConfigValue: types.StringNull(), StateValue: types.StringValue("0777")}, resp)
+	if resp.PlanValue.ValueString() != "0777" {
+		t.Errorf("expected existing files to keep their permission, got %s", resp.PlanValue)
+	}
+
+	resp = &planmodifier.StringResponse{PlanValue: types.StringValue("0640")}
+	m.PlanModifyString(ctx, planmodifier.StringRequest{ConfigValue: types.StringValue("0640"), StateValue: types.StringValue("0777")}, resp)
+	if resp.PlanValue.ValueString() != "0640" {
+		t.Errorf("expected the configured permission, got %s", resp.PlanValue)
+	}
+}
+
+func TestResourceFileModel_setFileAttributes(t *testing.T) {
+	ctx := context.Background()
+	content, err := ioutil.ReadFile(filepath.Join("test-fixtures", "basic.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := resourceFileModel{
+		Filename:      types.StringValue("basic.yaml"),
+		Content:       types.StringValue("hello: world\n"),
+		ContentObject: types.DynamicNull(),
+	}
+	if diags := model.setFileAttributes(ctx, content); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if model.EncryptedSha256.ValueString() != sha256Hex(content) {
+		t.Errorf("unexpected encrypted_sha256 %s", model.EncryptedSha256)
+	}
+	if model.ContentSha256.ValueString() != sha256Hex([]byte("hello: world\n")) {
+		t.Errorf("unexpected content_sha256 %s", model.ContentSha256)
+	}
+	if model.SopsVersion.ValueString() != "3.2.0" || model.LastModified.ValueString() != "2019-04-26T18:43:59Z" {
+		t.Errorf("unexpected metadata %s, %s", model.SopsVersion, model.LastModified)
+	}
+	var recipients []string
+	model.Recipients.ElementsAs(ctx, &recipients, false)
+	if len(recipients) != 1 || recipients[0] != testPgpFingerprint {
+		t.Errorf("expected the PGP fingerprint as recipient, got %v", recipients)
+	}
+}
@@ -1,13 +1,20 @@
 package sops
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	scommon "go.mozilla.org/sops/v3/cmd/sops/common"
+	scommon "github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/getsops/sops/v3/config"
 )
 
-func GetInputStore(d *schema.ResourceData) scommon.Store {
-	return scommon.DefaultStoreForPathOrFormat(d.Get("filename").(string), "file")
+func GetInputStore(filename string) scommon.Store {
+	return scommon.DefaultStoreForPathOrFormat(config.NewStoresConfig(), filename, "file")
 }
-func GetOutputStore(d *schema.ResourceData) scommon.Store {
-	return scommon.DefaultStoreForPathOrFormat(d.Get("filename").(string), "file")
+func GetOutputStore(filename string) scommon.Store {
+	return scommon.DefaultStoreForPathOrFormat(config.NewStoresConfig(), filename, "file")
+}
+
+// storeForFormat returns the store of format with the default settings of
+// the sops CLI
+func storeForFormat(format formats.Format) scommon.Store {
+	return scommon.StoreForFormat(format, config.NewStoresConfig())
 }
@@ -1,6 +1,23 @@
 package sops
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"github.com/getsops/sops/v3/azkv"
+	"github.com/getsops/sops/v3/hcvault"
+)
+
+var (
+	sha256Pattern         = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+	kmsArnPattern         = regexp.MustCompile(`^arn:aws[a-z-]*:kms:[a-z0-9-]+:\d{12}:(key|alias)/[a-zA-Z0-9/_-]+$`)
+	iamRoleArnPattern     = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+	gcpKmsResourcePattern = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+$`)
+	pgpFingerprintPattern = regexp.MustCompile(`^[0-9a-fA-F]{16}([0-9a-fA-F]{24})?$`)
+)
 
 // validateInputType ensures that we can decode the input
 func validateInputType(inputType string) error {
@@ -16,6 +33,130 @@ func validateInputType(inputType string) error {
 	case "raw":
 		return nil
 	default:
-		return fmt.Errorf("Don't know how to decode file with input type %s, set input_type to json, yaml, ini, dotenv or raw as appropriate", inputType)
+		return fmt.Errorf("Don't know how to decode file with input type %s, set input_type to json, yaml, ini, dotenv, raw or auto as appropriate", inputType)
+	}
+}
+
+// validateOutputType ensures that we can encode the output
+func validateOutputType(outputType string) error {
+	switch outputType {
+	case "json", "yaml", "dotenv", "ini", "raw":
+		return nil
+	}
+	return fmt.Errorf("Don't know how to encode file with output type %s, set output_type to json, yaml, ini, dotenv or raw", outputType)
+}
+
+// validateEncryptionType ensures that encryptionType is one of the fixed key
+// combinations supported before recipients could be combined freely
+func validateEncryptionType(encryptionType string) error {
+	switch encryptionType {
+	case "kms", "gcpkms", "age", "mix":
+		return nil
+	}
+	return fmt.Errorf("unknown encryption_type %q, expected kms, gcpkms, age or mix", encryptionType)
+}
+
+// validateValueType ensures that valueType is a way sops_file_key can write
+// its value
+func validateValueType(valueType string) error {
+	switch valueType {
+	case "string", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown value_type %q, expected string or json", valueType)
+}
+
+// validateKmsArn ensures that arn is the ARN of a KMS key or alias,
+// optionally followed by + and the ARN of a role to assume as sops allows
+func validateKmsArn(arn string) error {
+	if i := strings.Index(arn, "+"); i > 0 {
+		if err := validateIamRoleArn(arn[i+1:]); err != nil {
+			return err
+		}
+		arn = arn[:i]
+	}
+	if !kmsArnPattern.MatchString(arn) {
+		return fmt.Errorf("%q is not the ARN of a KMS key, expected arn:aws:kms:<region>:<account>:key/<id>", arn)
+	}
+	return nil
+}
+
+// validateIamRoleArn ensures that arn is the ARN of an IAM role
+func validateIamRoleArn(arn string) error {
+	if !iamRoleArnPattern.MatchString(arn) {
+		return fmt.Errorf("%q is not the ARN of an IAM role, expected arn:aws:iam::<account>:role/<name>", arn)
+	}
+	return nil
+}
+
+// validateAgeRecipient ensures that recipient is a bech32-encoded age public
+// key or an ssh-ed25519 or ssh-rsa public key, which sops encrypts for with
+// age as well
+func validateAgeRecipient(recipient string) error {
+	if strings.HasPrefix(recipient, "ssh-") {
+		if _, err := agessh.ParseRecipient(recipient); err != nil {
+			return fmt.Errorf("%q is not an SSH public key supported by age: %s", recipient, err)
+		}
+		return nil
+	}
+	if _, err := age.ParseX25519Recipient(recipient); err != nil {
+		return fmt.Errorf("%q is not an age public key: %s", recipient, err)
+	}
+	return nil
+}
+
+// validateGcpKmsResourceID ensures that id is the resource ID of a GCP KMS
+// crypto key
+func validateGcpKmsResourceID(id string) error {
+	if !gcpKmsResourcePattern.MatchString(id) {
+		return fmt.Errorf("%q is not a GCP KMS key resource ID, expected projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>", id)
+	}
+	return nil
+}
+
+// validatePgpFingerprint ensures that fingerprint is the fingerprint or long
+// key ID of a PGP key
+func validatePgpFingerprint(fingerprint string) error {
+	if !pgpFingerprintPattern.MatchString(fingerprint) {
+		return fmt.Errorf("%q is not a PGP key, expected a 40-digit fingerprint or 16-digit key ID", fingerprint)
+	}
+	return nil
+}
+
+// validateAzkvURL ensures that url is the URL of an Azure Key Vault key
+// version
+func validateAzkvURL(url string) error {
+	if _, err := azkv.NewMasterKeyFromURL(url); err != nil {
+		return fmt.Errorf("%q is not an Azure Key Vault key URL, expected https://<vault>.vault.azure.net/keys/<key>/<version>", url)
+	}
+	return nil
+}
+
+// validateVaultURI ensures that uri is the URI of a HashiCorp Vault transit
+// key
+func validateVaultURI(uri string) error {
+	if _, err := hcvault.NewMasterKeyFromURI(uri); err != nil {
+		return fmt.Errorf("%q is not a Vault transit key URI: %s", uri, err)
+	}
+	return nil
+}
+
+// validateRegex ensures that pattern compiles as sops would compile it
+func validateRegex(pattern string) error {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("%q is not a valid regular expression: %s", pattern, err)
+	}
+	return nil
+}
+
+// commaSeparated applies validate to every item of a comma separated list
+func commaSeparated(validate func(string) error) func(string) error {
+	return func(list string) error {
+		for _, item := range strings.Split(list, ",") {
+			if err := validate(strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 }
@@ -1,6 +1,9 @@
 package sops
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func testValidateInputType(inputType string, t *testing.T) {
 	err := validateInputType(inputType)
@@ -31,3 +34,104 @@ func TestValidateInputType_bad(t *testing.T) {
 		t.Errorf("Failed to validate input type %s, expected to be invalid but was valid", inputType)
 	}
 }
+
+func TestValidateKmsArn(t *testing.T) {
+	valid := []string{
+		"arn:aws:kms:us-east-1:123456789012:key/d7b1a4b5-1d3e-4ae4-9c32-5b9e1a5f5c3e",
+		"arn:aws-us-gov:kms:us-gov-west-1:123456789012:alias/secrets",
+		"arn:aws:kms:us-east-1:123456789012:key/abc+arn:aws:iam::123456789012:role/sops",
+	}
+	for _, arn := range valid {
+		if err := validateKmsArn(arn); err != nil {
+			t.Errorf("expected %s to be valid: %s", arn, err)
+		}
+	}
+	invalid := []string{
+		"",
+		"arn:aws:iam::123456789012:role/admin",
+		"arn:aws:kms:us-east-1:1234:key/abc",
+		"arn:aws:kms:us-east-1:123456789012:key/abc+arn:aws:kms:us-east-1:123456789012:key/def",
+	}
+	for _, arn := range invalid {
+		if err := validateKmsArn(arn); err == nil {
+			t.Errorf("expected %s to be invalid", arn)
+		}
+	}
+}
+
+func TestValidateIamRoleArn(t *testing.T) {
+	if err := validateIamRoleArn("arn:aws:iam::123456789012:role/sops/encrypt"); err != nil {
+		t.Errorf("expected a role ARN to be valid: %s", err)
+	}
+	if err := validateIamRoleArn("arn:aws:kms:us-east-1:123456789012:key/abc"); err == nil {
+		t.Error("expected a KMS ARN to be invalid")
+	}
+}
+
+func TestValidateAgeRecipient(t *testing.T) {
+	if err := validateAgeRecipient("age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"); err != nil {
+		t.Errorf("expected the recipient to be valid: %s", err)
+	}
+	invalid := []string{
+		"",
+		"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8q",
+		"AGE-SECRET-KEY-1QQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQ",
+	}
+	for _, recipient := range invalid {
+		if err := validateAgeRecipient(recipient); err == nil {
+			t.Errorf("expected %s to be invalid", recipient)
+		}
+	}
+}
+
+func TestValidateGcpKmsResourceID(t *testing.T) {
+	if err := validateGcpKmsResourceID("projects/p/locations/global/keyRings/r/cryptoKeys/k"); err != nil {
+		t.Errorf("expected the resource ID to be valid: %s", err)
+	}
+	if err := validateGcpKmsResourceID("projects/p/locations/global/keyRings/r"); err == nil {
+		t.Error("expected a key ring ID to be invalid")
+	}
+}
+
+func TestValidatePgpFingerprint(t *testing.T) {
+	for _, fp := range []string{"3CE5CC7219D6597CE6488BF1BF36CD3D0749A11A", "BF36CD3D0749A11A"} {
+		if err := validatePgpFingerprint(fp); err != nil {
+			t.Errorf("expected %s to be valid: %s", fp, err)
+		}
+	}
+	err := validatePgpFingerprint("0749A11A")
+	if err == nil {
+		t.Fatal("expected a short key ID to be invalid")
+	}
+	if !strings.Contains(err.Error(), "40-digit fingerprint or 16-digit key ID") {
+		t.Errorf("expected the error to name both accepted forms, got %q", err)
+	}
+}
+
+func TestValidateAzkvURL(t *testing.T) {
+	if err := validateAzkvURL("https://vault.vault.azure.net/keys/key/0123456789abcdef"); err != nil {
+		t.Errorf("expected the URL to be valid: %s", err)
+	}
+	if err := validateAzkvURL("https://vault.vault.azure.net/keys/key"); err == nil {
+		t.Error("expected a URL without version to be invalid")
+	}
+}
+
+func TestValidateVaultURI(t *testing.T) {
+	if err := validateVaultURI("https://vault.example.com:8200/v1/transit/keys/key"); err != nil {
+		t.Errorf("expected the URI to be valid: %s", err)
+	}
+	if err := validateVaultURI("https://vault.example.com:8200/v1/transit"); err == nil {
+		t.Error("expected a URI without key to be invalid")
+	}
+}
+
+func TestCommaSeparated(t *testing.T) {
+	validate := commaSeparated(validateKmsArn)
+	if err := validate("arn:aws:kms:us-east-1:123456789012:key/a, arn:aws:kms:us-east-1:123456789012:key/b"); err != nil {
+		t.Errorf("expected the list to be valid: %s", err)
+	}
+	if err := validate("arn:aws:kms:us-east-1:123456789012:key/a,arm:aws"); err == nil {
+		t.Error("expected the list to be invalid")
+	}
+}
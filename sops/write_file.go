@@ -0,0 +1,119 @@
+package sops
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+// writeFileAtomic writes content to filename through a temporary file in the
+// same directory, so readers see either the previous or the new content but
+// never a partial write
+func writeFileAtomic(filename string, content []byte, mode os.FileMode) error {
+	return writeFileAtomicOwner(filename, content, mode, -1, -1)
+}
+
+// writeFileAtomicOwner is writeFileAtomic also setting the owner and group of
+// the file to uid and gid, unless they are -1
+func writeFileAtomicOwner(filename string, content []byte, mode os.FileMode, uid, gid int) (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if uid != -1 || gid != -1 {
+		if err = tmp.Chown(uid, gid); err != nil {
+			return err
+		}
+	}
+	if _, err = tmp.Write(content); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(filename))
+}
+
+// syncDir flushes the directory entry of dir to disk, so a rename into it
+// survives a crash. Windows cannot sync directories and commits renames
+// itself, so it is skipped there.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// lookupOwner resolves the user and group names or IDs of owner and group,
+// returning -1 for the ones that are empty
+func lookupOwner(owner, group string) (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if owner != "" {
+		if uid, err = strconv.Atoi(owner); err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return -1, -1, err
+			}
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return -1, -1, fmt.Errorf("user %s has no numeric ID", owner)
+			}
+		}
+	}
+	if group != "" {
+		if gid, err = strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return -1, -1, err
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return -1, -1, fmt.Errorf("group %s has no numeric ID", group)
+			}
+		}
+	}
+	return uid, gid, nil
+}
+
+// resolveSymlink returns the file to write in place of filename. Writing
+// replaces filename, so a symbolic link is refused unless follow is set, in
+// which case its target is written.
+func resolveSymlink(filename string, follow bool) (string, error) {
+	info, err := os.Lstat(filename)
+	if os.IsNotExist(err) {
+		return filename, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return filename, nil
+	}
+	if !follow {
+		return "", fmt.Errorf("%s is a symbolic link, set follow_symlinks to write to its target", filename)
+	}
+	return filepath.EvalSymlinks(filename)
+}
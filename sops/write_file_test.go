@@ -0,0 +1,91 @@
+package sops
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "secret.enc.yaml")
+	if err := writeFileAtomic(filename, []byte("hello: world\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %o", info.Mode().Perm())
+	}
+	entries, err := ioutil.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected the temporary file to be renamed, got %d files", len(entries))
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directories are not synced on windows")
+	}
+	if err := syncDir(t.TempDir()); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := syncDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestResolveSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.yaml")
+	if err := ioutil.WriteFile(target, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("can't create symbolic links: %s", err)
+	}
+
+	if _, err := resolveSymlink(link, false); err == nil {
+		t.Error("expected a symbolic link to be refused")
+	}
+	resolved, err := resolveSymlink(link, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, _ := filepath.EvalSymlinks(target); resolved != want {
+		t.Errorf("expected the target %s, got %s", want, resolved)
+	}
+	if resolved, err := resolveSymlink(filepath.Join(dir, "new.yaml"), false); err != nil || resolved != filepath.Join(dir, "new.yaml") {
+		t.Errorf("expected a missing file to be written as is, got %s, %v", resolved, err)
+	}
+}
+
+func TestLookupOwner(t *testing.T) {
+	uid, gid, err := lookupOwner("", "")
+	if err != nil || uid != -1 || gid != -1 {
+		t.Errorf("expected unset owner and group, got %d, %d, %v", uid, gid, err)
+	}
+	uid, gid, err = lookupOwner("1000", "1001")
+	if err != nil || uid != 1000 || gid != 1001 {
+		t.Errorf("expected numeric IDs to be used as is, got %d, %d, %v", uid, gid, err)
+	}
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("can't look up the current user: %s", err)
+	}
+	if uid, _, err := lookupOwner(current.Username, ""); err != nil || current.Uid != strconv.Itoa(uid) {
+		t.Errorf("expected %s to resolve to %s, got %d, %v", current.Username, current.Uid, uid, err)
+	}
+	if _, _, err := lookupOwner("no-such-user-for-sops", ""); err == nil {
+		t.Error("expected an unknown user to be rejected")
+	}
+}